@@ -5,9 +5,12 @@ set PIPER_EXE=tools\piper\piper.exe
 set PIPER_MODEL=tools\piper\en_US-joe-medium.onnx
 
 set TTSPATH=tts\\
+if defined WATCHERINO_TTSPATH set TTSPATH=%WATCHERINO_TTSPATH%
 set TTSMESSAGE=is now streaming.
 
-for /f "usebackq tokens=1,* delims==" %%A in ("config.txt") do (
+if not defined WATCHERINO_CONFIG set WATCHERINO_CONFIG=config.txt
+
+for /f "usebackq tokens=1,* delims==" %%A in ("%WATCHERINO_CONFIG%") do (
     set KEY=%%A
     set VAL=%%B
     if not "!KEY:~0,1!"=="#" if not "!KEY!"=="" (
@@ -18,16 +21,23 @@ for /f "usebackq tokens=1,* delims==" %%A in ("config.txt") do (
 
 if not exist "%TTSPATH%" mkdir "%TTSPATH%"
 
-for /f "usebackq tokens=1,* delims==" %%A in ("config.txt") do (
+for /f "usebackq tokens=1,* delims==" %%A in ("%WATCHERINO_CONFIG%") do (
     set KEY=%%A
     set VAL=%%B
-    if not "!KEY:~0,1!"=="#" if not "!KEY:~0,1!"=="$" if not "!KEY!"=="" (
+    rem alias:/ttsmsg: lines aren't channels, ttsmsg: overrides are generated by the app
+    if not "!KEY:~0,1!"=="#" if not "!KEY:~0,1!"=="$" if not "!KEY!"=="" if "!KEY!"=="!KEY::=!" (
 
         set TEXT=!KEY! %TTSMESSAGE%
         set OUTFILE=%TTSPATH%!KEY!.wav
         if not exist "!OUTFILE!" (
             echo Generating: !OUTFILE! ^("!TEXT!"^)
-            echo !TEXT! | "%PIPER_EXE%" --model "%PIPER_MODEL%" --output_file "!OUTFILE!"
+            rem write next to it and move into place, so a crash never leaves a partial wav
+            echo !TEXT! | "%PIPER_EXE%" --model "%PIPER_MODEL%" --output_file "!OUTFILE!.tmp"
+            if errorlevel 1 (
+                if exist "!OUTFILE!.tmp" del "!OUTFILE!.tmp"
+            ) else (
+                move /Y "!OUTFILE!.tmp" "!OUTFILE!" >nul
+            )
         ) else (
             echo Skipping ^(exists^): !OUTFILE!
         )
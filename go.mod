@@ -4,6 +4,7 @@ go 1.23
 
 require (
 	github.com/ebitengine/oto/v3 v3.3.3
+	github.com/go-audio/audio v1.0.0
 	github.com/go-audio/wav v1.1.0
 	github.com/wailsapp/wails/v2 v2.10.2
 	golang.org/x/image v0.12.0
@@ -12,7 +13,6 @@ require (
 require (
 	github.com/bep/debounce v1.2.1 // indirect
 	github.com/ebitengine/purego v0.8.0 // indirect
-	github.com/go-audio/audio v1.0.0 // indirect
 	github.com/go-audio/riff v1.0.0 // indirect
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
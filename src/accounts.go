@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Account is a Twitch login from account:name=nick,oauth:token
+type Account struct {
+	Nick       string `json:"nick"`
+	OauthToken string `json:"-"`
+}
+
+func (acc Account) valid() bool {
+	return acc.Nick != "" && acc.OauthToken != ""
+}
+
+// Names that aren't account: lines. "default" is $nick/$oauth.
+const (
+	defaultAccount   = "default"
+	anonymousAccount = "anonymous"
+)
+
+// account looks up name, "" meaning the default account
+func (config TwitchConfig) account(name string) (Account, bool) {
+	switch name {
+	case "", defaultAccount:
+		return Account{Nick: config.Nickname, OauthToken: config.OauthToken}, true
+	case anonymousAccount:
+		return Account{}, true
+	}
+	acc, ok := config.Accounts[name]
+	return acc, ok
+}
+
+// activeAccount is the account new connections log in with. Only one is
+// used at a time.
+func (a *App) activeAccount() (string, Account) {
+	a.accountMu.RLock()
+	name := a.account
+	a.accountMu.RUnlock()
+	acc, _ := a.config.account(name)
+	return name, acc
+}
+
+// GetAccounts lists the account names SetActiveAccount takes
+func (a *App) GetAccounts() []string {
+	names := []string{defaultAccount, anonymousAccount}
+	extra := make([]string, 0, len(a.config.Accounts))
+	for name := range a.config.Accounts {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+// SetActiveAccount logs in as another account ("anonymous" for none) and
+// reconnects every channel with it, keeping their buffers. Emits
+// "account-changed" with the new AuthStatus.
+func (a *App) SetActiveAccount(name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, ok := a.config.account(name); !ok {
+		return fmt.Errorf("unknown account %q", name)
+	}
+
+	a.accountMu.Lock()
+	if a.account == name {
+		a.accountMu.Unlock()
+		return nil
+	}
+	a.account = name
+	a.accountMu.Unlock()
+	logInfof("Switching to account %s", name)
+
+	a.connectionsMu.RLock()
+	connected := make([]string, 0, len(a.connections))
+	for channel, conn := range a.connections {
+		if !conn.replay {
+			connected = append(connected, channel)
+		}
+	}
+	a.connectionsMu.RUnlock()
+	slices.Sort(connected)
+
+	// Everything is dropped before redialing: with $multiplex the shared
+	// connection only logs in again once its last channel has left.
+	snaps := make(map[string]channelSnapshot, len(connected))
+	for _, channel := range connected {
+		if snap, ok := a.snapshotChannel(channel); ok {
+			snaps[channel] = snap
+			a.DisconnectFromChannel(channel)
+		}
+	}
+
+	var errs []error
+	for _, channel := range connected {
+		snap, ok := snaps[channel]
+		if !ok {
+			continue
+		}
+		if err := a.ConnectToChannel(channel); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+			continue
+		}
+		if err := a.restoreChannel(channel, snap); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// the new login's sets come with its USERSTATE
+	a.updateUserEmotes(nil)
+	a.emit("account-changed", a.GetAuthStatus())
+	return errors.Join(errs...)
+}
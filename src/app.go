@@ -1,52 +1,223 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ebitengine/oto/v3"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // a.channels normal, a.connections -> # obviously
 
 type TwitchConfig struct {
-	Nickname         string `json:"nickname"`
-	OauthToken       string `json:"oauthToken"`
-	FilterList       []string
-	RecordingEnabled bool
-	ArchiveDir       string
-	TTSPath          string
-	TTSMessage       string
+	Nickname           string `json:"nickname"`
+	OauthToken         string `json:"oauthToken"`
+	Account            string `json:"account"` // starting account, empty = $nick/$oauth
+	FilterList         []string
+	Aliases            map[string]string // login -> display name, UI only
+	TTSMessages        map[string]string // login -> live announcement, overrides TTSMessage
+	IgnoredUsers       map[string]bool   // logins whose messages aren't shown
+	IgnoreInLogs       bool              // don't log ignored users' messages either
+	RecordingEnabled   bool
+	ArchiveDir         string
+	DataDir            string // root for logs, emotes, tts, exports; empty = working dir
+	Console            bool   // also log to a console, like -console
+	LogLevel           logLevel
+	TTSPath            string
+	TTSMessage         string
+	AudioFollowsActive bool // stream audio switches along with the active chat
+	QuietHours         QuietHours
+	HighlightFirst     bool
+	HighlightWebhook   string // URL POSTed on highlights
+	HighlightExec      string // command run on highlights
+	AutoSwitch         bool   // switch to channels as they go live
+	ReconnectOnLive    bool   // check chat is flowing when a channel goes live
+	NotifyHighlights   bool   // desktop notification on highlights
+	NotifyLive         bool   // desktop notification when a channel goes live
+	CollapseRepeats    bool
+	CollapseWindow     time.Duration
+	SevenTVExclude     int             // bitmask of sevenTVFlags
+	SevenTVMax         int             // max 7TV emotes per channel, 0 = no cap
+	SevenTVScale       int             // preferred 7TV source size, 1-4 for 1x-4x, 0 = largest
+	EmoteCacheSize     int             // max Twitch emotes kept in memory, 0 = no cap
+	EmoteBlacklist     map[string]bool // emote names never downloaded or rendered
+	EmotesOff          bool            // text only, no emotes fetched, downloaded or parsed
+	SendRateLimit      int
+	SendRateWindow     time.Duration
+	JoinRateLimit      int
+	JoinRateWindow     time.Duration
+	PollInterval       time.Duration // between live status checks
+	PollStagger        time.Duration // between channels within a check
+	HighlightCooldown  time.Duration // min time between highlight dings, 0 = none
+	ConnectConcurrency int           // connections opened at once on startup
+	ConnectStagger     time.Duration // between starting connections on startup
+	TimeFormat         string        // Go layout for message timestamps
+	LogDate            bool          // prefix log lines with the date
+	Background         string        // chat background, #rrggbb
+	MinContrast        float64       // min username contrast ratio, 0 = default blend
+	Palette            []string      // default username colors, empty = Twitch's
+	DefaultChannel     string        // login shown first on startup, empty = first listed
+	ChannelOrder       []string      // channel logins in the order config.txt lists them
+	Firehose           bool          // emit multi-message/-reward-redemption for every channel
+	RawIRC             bool          // debug stream of every IRC line
+	ModLog             bool          // write bans/timeouts/deletes to a mod log
+	Membership         bool          // request JOIN/PART of other users
+	HideLinks          string        // "mask" or "strip" links in displayed messages
+	TruncateLength     int           // messages longer than this are collapsed, 0 = never
+	BufferSize         int           // messages kept per channel
+	EmotePriority      []string      // providers in lookup order, e.g. 7tv,bttv,ffz
+	IRCServer          string
+	IRCPort            int  // 0 = 6667, or 6697 with IRCTLS
+	IRCTLS             bool // dial chat over TLS
+	Multiplex          bool // all channels over one IRC connection
+
+	// account:name=... logins, besides $nick/$oauth
+	Accounts map[string]Account
 }
 
 // ChannelConnection represents a connection to a single Twitch channel
 type ChannelConnection struct {
 	channel     string
+	bufferSize  int    // max len(messages)
+	roomID      string // set from the first message's room-id tag
 	client      *Client
 	cancel      context.CancelFunc
 	messages    []map[string]interface{}
 	viewerCount int
+	viewers     *ViewerHistory
+	recent      map[string]*repeatedLine // normalized content -> line, for spam collapse
 	isConnected bool
+	replay      bool      // fed by ReplayLog, not logged again
+	paused      bool      // buffer and log but don't emit new-message
+	lastErr     error     // last error from the client, kept across reconnects
+	lastErrAt   time.Time // when lastErr happened
 	mu          sync.RWMutex
 }
 
+// channelError is an error from a channel's client and when it happened
+type channelError struct {
+	err error
+	at  time.Time
+}
+
+// repeatedLine tracks a message that later identical messages collapse into
+type repeatedLine struct {
+	id       string
+	count    int
+	lastSeen time.Time
+}
+
+func normalizeContent(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+// collapseRepeat checks whether content repeats a message seen within
+// window ($collapsewindow). If so it bumps that message's count in
+// conn.messages and returns the updated msgData; otherwise it starts
+// tracking msgData.
+// Caller must hold conn.mu.
+func (conn *ChannelConnection) collapseRepeat(msgData map[string]interface{}, content string, now time.Time, window time.Duration) (map[string]interface{}, bool) {
+	for key, line := range conn.recent {
+		if now.Sub(line.lastSeen) > window {
+			delete(conn.recent, key)
+		}
+	}
+
+	key := normalizeContent(content)
+	id, _ := msgData["id"].(string)
+	line, exists := conn.recent[key]
+	if !exists {
+		conn.recent[key] = &repeatedLine{id: id, count: 1, lastSeen: now}
+		return nil, false
+	}
+
+	line.count++
+	line.lastSeen = now
+	for i := len(conn.messages) - 1; i >= 0; i-- {
+		if conn.messages[i]["id"] != line.id {
+			continue
+		}
+		// copy rather than mutate, the old map may be getting serialized by an emit
+		updated := make(map[string]interface{}, len(conn.messages[i])+1)
+		for k, v := range conn.messages[i] {
+			updated[k] = v
+		}
+		updated["repeatCount"] = line.count
+		conn.messages[i] = updated
+		return updated, true
+	}
+
+	// the original already fell out of the buffer, start over from this one
+	conn.recent[key] = &repeatedLine{id: id, count: 1, lastSeen: now}
+	return nil, false
+}
+
+const (
+	viewerPollInterval = 30 * time.Second
+	// 2 hours of samples at the poll interval
+	viewerHistorySize = int(2 * time.Hour / viewerPollInterval)
+)
+
+// ViewerSample is a single viewer count reading
+type ViewerSample struct {
+	Timestamp int64 `json:"timestamp"` // unix ms
+	Count     int   `json:"count"`
+}
+
+// ViewerHistory is a fixed-size ring of viewer samples
+type ViewerHistory struct {
+	samples []ViewerSample
+	next    int
+	full    bool
+}
+
+func NewViewerHistory(size int) *ViewerHistory {
+	return &ViewerHistory{samples: make([]ViewerSample, size)}
+}
+
+func (vh *ViewerHistory) Add(sample ViewerSample) {
+	vh.samples[vh.next] = sample
+	vh.next = (vh.next + 1) % len(vh.samples)
+	if vh.next == 0 {
+		vh.full = true
+	}
+}
+
+// Samples returns the history oldest first
+func (vh *ViewerHistory) Samples() []ViewerSample {
+	if !vh.full {
+		return append([]ViewerSample(nil), vh.samples[:vh.next]...)
+	}
+	result := make([]ViewerSample, 0, len(vh.samples))
+	result = append(result, vh.samples[vh.next:]...)
+	return append(result, vh.samples[:vh.next]...)
+}
+
 // EmoteSearchResult is returned to the frontend for autocomplete.
 type EmoteSearchResult struct {
 	Name     string `json:"name"`
 	FilePath string `json:"filePath"`
-	Source   string `json:"source"`
+	Source   string `json:"source"`   // provider, "-global" for global sets
+	Provider string `json:"provider"` // 7tv, bttv, ffz or twitch
+	Scope    string `json:"scope"`    // channel or global
 }
 
+const frontendReadyTimeout = 10 * time.Second
+
 // App represents the app state with all channels and connections
 type App struct {
 	ctx           context.Context
@@ -58,49 +229,202 @@ type App struct {
 	liveStatuses   map[string]bool
 	statusTicker   *time.Ticker
 	stopMonitoring chan bool
+
+	// Why Twitch refused a channel's JOIN. The connection is dropped then,
+	// this keeps the reason for diagnostics. Guarded by connectionsMu.
+	joinErrors map[string]channelError
+
+	// set while ConnectToAllChannels runs, it picks the active channel itself
+	autoConnecting atomic.Bool
+	// set in OnBeforeClose, background goroutines stop emitting
+	shuttingDown atomic.Bool
+
+	// Whispers from every connection, deduplicated. Oldest first.
+	whispers   []Whisper
+	whispersMu sync.Mutex
+
+	// Logins from $ignore and IgnoreUser
+	ignored   map[string]bool
+	ignoredMu sync.RWMutex
+
+	// From config.txt, read once at startup. Problems don't stop the app,
+	// they're kept in configErr and shown once the window is up.
+	config        TwitchConfig
+	configPath    string
+	configErr     error
+	channelConfig map[string]ChannelSettings
+	filterList    []string
+	recording     bool
+	archiveDir    string
+
+	// Messages kept per channel. Changed through SetBufferSize, existing
+	// connections keep the size they were created with.
+	bufferSize atomic.Int32
+	firehose   atomic.Bool // toggled through SetFirehose
+	rawIRC     atomic.Bool // toggled through SetRawIRC
+
+	// Every JOIN, including the ones on reconnect, goes through joinLimiter.
+	// Twitch drops clients that JOIN too fast ($joinrate, 20/10 for normal
+	// accounts). mux carries every channel with $multiplex.
+	joinLimiter *RateLimiter
+	mux         *ircMux
+
+	// The account new connections log in with, see activeAccount
+	account   string
+	accountMu sync.RWMutex
+
+	prefs   Preferences // prefs.json
+	prefsMu sync.RWMutex
+
+	// Caches and counters, their types say what they hold
+	metrics          appMetrics
+	gqlBreaker       circuitBreaker
+	emoteCache       *emoteLRU
+	emoteDownloads   *inFlight
+	refreshingEmotes atomic.Bool
+	userEmotes       userEmoteSets
+	chatters         chattersCache
+	streamInfo       streamInfoCache
+	sharedChatRooms  roomLogins
+	notifyLast       notifyLog
+	liveHistoryMu    sync.Mutex
+	rawIRCLog        rawLog
+
+	highlightLimiter *RateLimiter // $highlightwebhook/$highlightexec runs
+	lastDing         atomic.Int64 // unix nanos, for $highlightcooldown
+
+	loggers   map[string]*chatLog // channel -> today's chat log
+	loggersMu sync.Mutex
+
+	// Alerts and TTS, nil when there's no audio device (audioOutErr says why)
+	audioOut      *oto.Context
+	audioOutErr   error
+	audioWarnOnce sync.Once
+
+	// ttsAvailable is false when piper or its voice model is missing. Chat
+	// and the ding still work, live announcements just play whatever was
+	// generated before (usually nothing).
+	ttsAvailable bool
+
+	// streamlink processes to kill on exit
+	streamlinkPids   []int
+	pidsMu           sync.Mutex
+	activeRecordings atomic.Int32
+
+	// Stream audio. The recorder's channel is "none" until one is picked.
+	audio       *TwitchRecorder
+	audioMu     sync.Mutex
+	audioMuted  bool
+	audioLocked bool
 }
 
-func NewApp() *App {
+// NewApp sets up the app for config, as read from configPath. channelConfig
+// are the channels to connect to, configErr what went wrong reading them.
+func NewApp(configPath string, config TwitchConfig, channelConfig map[string]ChannelSettings, configErr error) *App {
 	channels := make([]string, 0)
 	// TODO Add tts on/off
-	for x, _ := range channels_map {
+	for x, _ := range channelConfig {
 		channels = append(channels, x)
 	}
+	// connect in config order, the map lost it
+	sort.SliceStable(channels, func(i, j int) bool {
+		return slices.Index(config.ChannelOrder, channels[i]) < slices.Index(config.ChannelOrder, channels[j])
+	})
 
-	return &App{
-		channels:       channels,
-		connections:    make(map[string]*ChannelConnection),
-		liveStatuses:   make(map[string]bool),
-		stopMonitoring: make(chan bool),
+	a := &App{
+		channels:         channels,
+		connections:      make(map[string]*ChannelConnection),
+		liveStatuses:     make(map[string]bool),
+		joinErrors:       make(map[string]channelError),
+		stopMonitoring:   make(chan bool),
+		config:           config,
+		configPath:       configPath,
+		configErr:        configErr,
+		channelConfig:    channelConfig,
+		filterList:       config.FilterList,
+		recording:        config.RecordingEnabled,
+		archiveDir:       cmp.Or(config.ArchiveDir, config.DataDir),
+		account:          cmp.Or(config.Account, defaultAccount),
+		joinLimiter:      NewRateLimiter(config.JoinRateLimit, config.JoinRateWindow),
+		emoteCache:       newEmoteLRU(config.EmoteCacheSize),
+		emoteDownloads:   &inFlight{paths: make(map[string]bool)},
+		chatters:         chattersCache{entries: make(map[string]cachedChatters)},
+		streamInfo:       streamInfoCache{entries: make(map[string]cachedStreamInfo)},
+		sharedChatRooms:  roomLogins{logins: make(map[string]string), pending: make(map[string]bool)},
+		notifyLast:       notifyLog{sent: make(map[string]time.Time)},
+		highlightLimiter: NewRateLimiter(highlightActionsPerMinute, time.Minute),
+		loggers:          make(map[string]*chatLog),
+		audio:            NewTwitchRecorder("none", "none"),
+	}
+	a.mux = newIRCMux(&a.config, a.joinLimiter)
+	a.bufferSize.Store(int32(config.BufferSize))
+	a.firehose.Store(config.Firehose)
+	a.rawIRC.Store(config.RawIRC)
+	a.prefs = loadPreferences(a.dataPath("prefs.json"))
+	a.audio.onAudioEnd = a.emitAudioState
+	a.ignored = a.loadIgnoredUsers()
+	return a
+}
+
+// dataPath joins elem onto $datadir, the working directory unless set
+func (a *App) dataPath(elem ...string) string {
+	return filepath.Join(append([]string{a.config.DataDir}, elem...)...)
+}
+
+// newClient is NewClient sharing the app's JOIN rate limit, raw IRC toggle
+// and, with $multiplex, its connection
+func (a *App) newClient(channel string, bufferSize int) *Client {
+	client := NewClient(channel, bufferSize, &a.config)
+	client.joinLimiter = a.joinLimiter
+	client.rawIRC = &a.rawIRC
+	if a.config.Multiplex {
+		client.mux = a.mux
 	}
+	return client
 }
 
 func (a *App) OnStartup(ctx context.Context) {
 	a.ctx = ctx
+
+	if errs := a.GetConfigErrors(); len(errs) > 0 {
+		go runtime.MessageDialog(ctx, runtime.MessageDialogOptions{
+			Type:    runtime.WarningDialog,
+			Title:   "Problems in config",
+			Message: strings.Join(errs, "\n") + "\n\nFix " + a.configPath + " and restart. Running with defaults for now.",
+		})
+	}
+
+	// Events emitted before the frontend registers its listeners are lost
+	frontendReady := make(chan struct{})
+	runtime.EventsOnce(ctx, "frontend-ready", func(...interface{}) {
+		close(frontendReady)
+	})
+
 	go func() {
-		log.Printf("Waiting 2 more seconds for live status checks...")
-		time.Sleep(2 * time.Second)
+		select {
+		case <-frontendReady:
+		case <-time.After(frontendReadyTimeout):
+			logInfof("No frontend-ready after %v, starting anyway", frontendReadyTimeout)
+		}
 
-		log.Printf("Auto-connecting to all channels...")
+		logInfof("Auto-connecting to all channels...")
 		if err := a.ConnectToAllChannels(); err != nil {
-			log.Printf("Auto-connection errors: %v", err)
+			logWarnf("Auto-connection errors: %v", err)
 		} else {
-			log.Printf("Auto-connection completed successfully")
+			logInfof("Auto-connection completed successfully")
 		}
-		log.Printf("Waiting 5 seconds for frontend to initialize...")
-		time.Sleep(2 * time.Second)
 
-		log.Printf("Starting live status monitoring...")
+		logInfof("Starting live status monitoring...")
 		go a.startLiveStatusMonitoring()
 
 	}()
 }
 
 func (a *App) ConnectToAllChannels() error {
-	log.Printf("ConnectToAllChannels called - connecting to %d channels...", len(a.channels))
+	logDebugf("ConnectToAllChannels called - connecting to %d channels...", len(a.channels))
 
 	if len(a.channels) == 0 {
-		log.Printf("No channels configured, skipping auto-connect")
+		logInfof("No channels configured, skipping auto-connect")
 		return nil
 	}
 
@@ -108,38 +432,53 @@ func (a *App) ConnectToAllChannels() error {
 	errors := make(chan error, len(a.channels))
 	successes := make(chan string, len(a.channels))
 
+	// JOINs are paced by joinLimiter inside Connect
+	slots := make(chan struct{}, a.config.ConnectConcurrency)
+
+	// Whichever connects first would otherwise become active. The startup
+	// channel is shown as soon as it's up, the rest are picked in order
+	// once everything has finished.
+	a.autoConnecting.Store(true)
+	defer a.autoConnecting.Store(false)
+	startup := a.startupChannel()
+
 	for i, channel := range a.channels {
-		log.Printf("Starting connection to channel %d/%d: %s", i+1, len(a.channels), channel)
+		slots <- struct{}{}
+		logDebugf("Starting connection to channel %d/%d: %s", i+1, len(a.channels), channel)
 
 		wg.Add(1)
 		go func(ch string, index int) {
 			defer wg.Done()
+			defer func() { <-slots }()
 
-			log.Printf("Connecting to %s (goroutine %d)...", ch, index+1)
+			logDebugf("Connecting to %s (goroutine %d)...", ch, index+1)
 
 			if err := a.ConnectToChannel(ch); err != nil {
-				log.Printf("Failed to auto-connect to %s: %v", ch, err)
+				logWarnf("Failed to auto-connect to %s: %v", ch, err)
 				errors <- fmt.Errorf("failed to connect to %s: %w", ch, err)
 				return
 			}
 
-			log.Printf("Successfully auto-connected to channel: %s", ch)
+			logDebugf("Successfully auto-connected to channel: %s", ch)
+			if ch == startup {
+				a.SetActiveChannel(ch)
+			}
 			successes <- ch
 		}(channel, i)
 
 		if i < len(a.channels)-1 {
-			time.Sleep(200 * time.Millisecond)
+			time.Sleep(a.config.ConnectStagger)
 		}
 	}
 
-	log.Printf("Waiting for all %d connection attempts to complete...", len(a.channels))
+	logDebugf("Waiting for all %d connection attempts to complete...", len(a.channels))
 
 	// Wait for all connections to complete
 	go func() {
 		wg.Wait()
 		close(errors)
 		close(successes)
-		log.Printf("All connection attempts finished")
+		logDebugf("All connection attempts finished")
 	}()
 
 	var connectionErrors []string
@@ -166,62 +505,120 @@ func (a *App) ConnectToAllChannels() error {
 		}
 	}
 
-	log.Printf("-> Auto-connection results:")
-	log.Printf("   Successful: %d channels - %v", len(successfulConnections), successfulConnections)
-	log.Printf("   Failed: %d channels - %v", len(connectionErrors), connectionErrors)
+	if a.GetActiveChannel() == "" {
+		// startup channel failed, fall back to the first one that didn't
+		for _, ch := range a.channels {
+			if a.SetActiveChannel(ch) == nil {
+				break
+			}
+		}
+	}
+
+	logDebugf("-> Auto-connection results:")
+	logDebugf("   Successful: %d channels - %v", len(successfulConnections), successfulConnections)
+	logDebugf("   Failed: %d channels - %v", len(connectionErrors), connectionErrors)
 
 	if len(connectionErrors) > 0 && len(successfulConnections) == 0 {
 		return fmt.Errorf("all connections failed: %v", connectionErrors)
 	} else if len(connectionErrors) > 0 {
 		// TODO redo
-		log.Printf("Some connections failed, but %d succeeded", len(successfulConnections))
+		logWarnf("Some connections failed, but %d succeeded", len(successfulConnections))
 	} else {
-		log.Printf("All channels connected successfully!")
+		logInfof("All channels connected successfully!")
 	}
 
 	return nil
 }
 
+// startupChannel is the channel to show first, $defaultchannel or the
+// first one in config.txt
+func (a *App) startupChannel() string {
+	if a.config.DefaultChannel != "" {
+		return a.config.DefaultChannel
+	}
+	if len(a.channels) > 0 {
+		return a.channels[0]
+	}
+	return ""
+}
+
+// ErrNoSuchChannel is returned by ConnectToChannel when Twitch has no user
+// with that login
+var ErrNoSuchChannel = errors.New("no such channel")
+
 func (a *App) ConnectToChannel(channel string) error {
 	originalChannel := channel
 
-	if !strings.HasPrefix(channel, "#") {
-		channel = "#" + channel
+	login, err := normalizeChannelName(channel)
+	if err != nil {
+		return err
 	}
+	channel = "#" + login
+
+	logDebugf("ConnectToChannel called: '%s' -> '%s'", originalChannel, channel)
 
-	log.Printf("ConnectToChannel called: '%s' -> '%s'", originalChannel, channel)
+	// Catch typos before dialing, IRC happily joins channels that don't exist.
+	// If Twitch can't be asked right now, connect anyway.
+	a.connectionsMu.RLock()
+	_, known := a.connections[channel]
+	a.connectionsMu.RUnlock()
+	if !known {
+		exists, err := a.channelExists(login)
+		if err != nil {
+			logWarnf("Couldn't check that %s exists: %v", login, err)
+		} else if !exists {
+			return fmt.Errorf("%w: %s", ErrNoSuchChannel, login)
+		}
+	}
 
 	a.connectionsMu.Lock()
 
 	if conn, exists := a.connections[channel]; exists && conn.isConnected {
-		log.Printf("Channel %s already connected, switching to it", channel)
+		logInfof("Channel %s already connected, switching to it", channel)
 		// just switch to this channel
 		a.activeChannel = channel
 		a.connectionsMu.Unlock()
 
-		runtime.EventsEmit(a.ctx, "channel-switched", channel)
+		a.emit("channel-switched", channel)
 		a.emitRecentMessages(channel)
 		return nil
 	}
+	// Connecting can wait on the JOIN limiter for a while, don't hold up
+	// everything else meanwhile
+	a.connectionsMu.Unlock()
 
-	log.Printf("Creating new connection for %s", channel)
+	logDebugf("Creating new connection for %s", channel)
+	size := int(a.bufferSize.Load())
 	conn := &ChannelConnection{
 		channel:     channel,
-		messages:    make([]map[string]interface{}, 0, bufferSize),
+		bufferSize:  size,
+		messages:    make([]map[string]interface{}, 0, size),
+		viewers:     NewViewerHistory(viewerHistorySize),
+		recent:      make(map[string]*repeatedLine),
 		isConnected: false,
 	}
 
-	log.Printf("Creating client for %s", channel)
-	conn.client = NewClient(channel, bufferSize)
+	logDebugf("Creating client for %s", channel)
+	conn.client = a.newClient(channel, size)
+	if _, account := a.activeAccount(); account.valid() {
+		conn.client.SetCredentials(account.Nick, account.OauthToken)
+	}
 
-	log.Printf("Attempting IRC connection to %s", channel)
+	logDebugf("Attempting IRC connection to %s", channel)
 	if err := conn.client.Connect(); err != nil {
-		a.connectionsMu.Unlock()
-		log.Printf("IRC connection failed for %s: %v", channel, err)
+		logWarnf("IRC connection failed for %s: %v", channel, err)
 		return fmt.Errorf("failed to connect to %s: %w", channel, err)
 	}
 
-	log.Printf("Starting client for %s", channel)
+	a.connectionsMu.Lock()
+	if existing, exists := a.connections[channel]; exists && existing.isConnected {
+		// another call connected it first
+		a.connectionsMu.Unlock()
+		conn.client.Stop()
+		return nil
+	}
+
+	logDebugf("Starting client for %s", channel)
 	conn.client.Start()
 	conn.isConnected = true
 
@@ -229,22 +626,23 @@ func (a *App) ConnectToChannel(channel string) error {
 	conn.cancel = cancel
 
 	a.connections[channel] = conn
+	delete(a.joinErrors, channel)
 
-	if a.activeChannel == "" {
-		log.Printf("Setting %s as active channel", channel)
+	if a.activeChannel == "" && !a.autoConnecting.Load() {
+		logDebugf("Setting %s as active channel", channel)
 		a.activeChannel = channel
 	}
 
 	a.connectionsMu.Unlock()
 
-	log.Printf("Starting message forwarding for %s", channel)
+	logDebugf("Starting message forwarding for %s", channel)
 	go a.forwardMessages(ctx, conn)
 
-	log.Printf("Starting viewer count monitoring for %s", channel)
+	logDebugf("Starting viewer count monitoring for %s", channel)
 	go a.monitorViewerCount(ctx, conn)
 
-	log.Printf("Successfully connected to channel: %s", channel)
-	runtime.EventsEmit(a.ctx, "channel-connected", channel)
+	logDebugf("Successfully connected to channel: %s", channel)
+	a.emit("channel-connected", channel)
 
 	return nil
 }
@@ -257,7 +655,7 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("forwardMessages recovered from panic for %s: %v", conn.channel, r)
+			logErrorf("forwardMessages recovered from panic for %s: %v", conn.channel, r)
 		}
 	}()
 
@@ -266,17 +664,32 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("Message forwarding cancelled for %s", conn.channel)
+			logDebugf("Message forwarding cancelled for %s", conn.channel)
 			return
 
 		case msg, ok := <-conn.client.MessageChannel():
 			if !ok {
-				log.Printf("Message channel closed for %s", conn.channel)
+				logDebugf("Message channel closed for %s", conn.channel)
 				return
 			}
+			a.metrics.countMessage(conn.channel)
 
-			if err := ProcessMessageEmotes(&msg); err != nil {
-				log.Printf("Error processing emotes: %v\n", err)
+			// Tag-only or malformed lines parse to nothing, don't show or log
+			// them as blank lines
+			if strings.TrimSpace(msg.Content) == "" {
+				continue
+			}
+
+			if a.isIgnored(msg.Login) {
+				if !a.config.IgnoreInLogs && !conn.replay {
+					a.logMessage(strings.TrimPrefix(conn.client.channel, "#"), msg)
+				}
+				continue
+			}
+
+			emotes, err := a.processMessageEmotes(&msg)
+			if err != nil {
+				logWarnf("Error processing emotes: %v\n", err)
 			}
 
 			// only fetch emotes when the first message is being received
@@ -289,49 +702,88 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 
 				channelID := msg.GetRoomID()
 				if channelID != "" {
-					go Fetch7TVEmotes(channelID, conn.client.channel)
-					go FetchBTTVChannelEmotes(channelID, conn.client.channel)
-					go FetchFFZChannelEmotes(channelID, conn.client.channel)
+					conn.mu.Lock()
+					conn.roomID = channelID
+					conn.mu.Unlock()
+
+					if !a.config.EmotesOff {
+						go a.fetch7TVEmotes(channelID, conn.client.channel)
+						go a.fetchBTTVChannelEmotes(channelID, conn.client.channel)
+						go a.fetchFFZChannelEmotes(channelID, conn.client.channel)
+					}
 					firstRun = false
 				}
 			}
 
-			emotes := ParseEmotes(&msg)
-			emoteInfo := make(map[string]string)
-			for _, emote := range emotes {
-				base64, err := a.GetEmoteBase64(emote.FilePath, emote, &msg)
-				if err != nil {
-					log.Printf("Error encoding emote: %v", err)
-					continue
-				}
-				emoteInfo[emote.Name] = base64
-			}
+			a.metrics.emotesParsed.Add(int64(len(emotes)))
+			rendered := a.encodeMessageEmotes(emotes, &msg)
 
 			msgData := map[string]interface{}{
-				"username":      msg.Username,
-				"content":       msg.Content,
-				"channel":       msg.Channel,
-				"timestamp":     msg.Timestamp.Format("15:04:05"),
-				"userColor":     msg.UserColor,
-				"emotes":        emoteInfo,
-				"isHighlighted": false,
-				"isUserNotice":  msg.isUserNotice,
+				"id":                 msg.Tags["id"],
+				"username":           msg.Username,
+				"login":              msg.Login,
+				"userId":             msg.UserID,
+				"content":            hideLinks(msg.Content, a.config.HideLinks),
+				"channel":            msg.Channel,
+				"timestamp":          a.formatTimestamp(msg.Timestamp),
+				"userColor":          msg.UserColor,
+				"emotes":             rendered.images,
+				"emoteSources":       rendered.sources,
+				"emoteSizes":         rendered.sizes,
+				"missingEmotes":      rendered.missing,
+				"isHighlighted":      false,
+				"isUserNotice":       msg.isUserNotice,
+				"badges":             msg.Badges,
+				"isBroadcaster":      msg.HasBadge("broadcaster"),
+				"isModerator":        msg.HasBadge("moderator"),
+				"isVip":              msg.HasBadge("vip"),
+				"isFirstMessage":     msg.IsFirstMessage,
+				"isReturningChatter": msg.IsReturningChatter,
+				"isAction":           msg.IsAction,
+			}
+			// the full content is still sent and logged, the UI shows the
+			// preview until expanded
+			if preview, ok := truncatePreview(msgData["content"].(string), a.config.TruncateLength); ok {
+				msgData["truncated"] = true
+				msgData["preview"] = preview
+			}
+			if msg.SourceRoomID != "" {
+				msgData["sourceRoomId"] = msg.SourceRoomID
+				msgData["sourceChannel"] = a.sourceChannel(msg.SourceRoomID)
 			}
 
-			channelToLog := strings.TrimPrefix(conn.client.channel, "#")
-			file, ok := loggerList[channelToLog]
-			if !ok {
-				// new
-				file = createFileForChannel(channelToLog)
-				loggerList[channelToLog] = file
+			if !conn.replay {
+				a.logMessage(strings.TrimPrefix(conn.client.channel, "#"), msg)
+			}
+
+			// Identical lines within the window are folded into the first one ("x42")
+			// rather than shown again. They're still logged above.
+			if a.config.CollapseRepeats && !msg.isUserNotice && msg.Tags["id"] != "" &&
+				!containsAny(msg.Content, a.filterList) {
+				conn.mu.Lock()
+				collapsed, ok := conn.collapseRepeat(msgData, msg.Content, msg.Timestamp, a.config.CollapseWindow)
+				paused := conn.paused
+				conn.mu.Unlock()
+
+				if ok {
+					a.connectionsMu.RLock()
+					isActive := (a.activeChannel == conn.channel)
+					a.connectionsMu.RUnlock()
+
+					if isActive && !paused {
+						a.emit("message-collapsed", map[string]interface{}{
+							"channel":     conn.channel,
+							"id":          collapsed["id"],
+							"repeatCount": collapsed["repeatCount"],
+						})
+					}
+					continue
+				}
 			}
-			fmt.Fprintf(file, "[%s] %s: %s\n", msg.Timestamp.Format("15:04:05"),
-				msg.Username, msg.Content)
-			file.Sync()
 
 			conn.mu.Lock()
 			conn.messages = append(conn.messages, msgData)
-			if len(conn.messages) > bufferSize {
+			if len(conn.messages) > conn.bufferSize {
 				conn.messages = conn.messages[1:] // Remove oldest
 			}
 			conn.mu.Unlock()
@@ -340,20 +792,38 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 			isActive := (a.activeChannel == conn.channel)
 			a.connectionsMu.RUnlock()
 
-			if containsAny(msg.Content, filterList) {
+			if containsAny(msg.Content, a.filterList) || (a.config.HighlightFirst && msg.IsFirstMessage) {
 				msgData["isHighlighted"] = true
-				go playWav(otoCtx, getMp3ForChannel("ding"), 0.10)
+				if a.channelSettings(conn.channel).Alert && a.dingAllowed(time.Now()) {
+					go a.playAlert(a.getMp3ForChannel("ding"), 0.10)
+				}
+				a.runHighlightActions(msg)
+				if a.config.NotifyHighlights {
+					a.notify("highlight:"+conn.channel, "Highlight in "+conn.channel,
+						msg.Username+": "+snippet(hideLinks(msg.Content, a.config.HideLinks)))
+				}
 			}
 
-			if isActive {
-				runtime.EventsEmit(a.ctx, "new-message", msgData)
+			conn.mu.RLock()
+			paused := conn.paused
+			conn.mu.RUnlock()
+
+			// paused channels keep buffering and logging, ResumeChannel sends
+			// the buffer over in one go
+			if isActive && !paused {
+				a.emit("new-message", msgData)
 			} else if !isActive && msgData["isHighlighted"] == true {
-				runtime.EventsEmit(a.ctx, "highlight-channel", msgData)
+				a.emit("highlight-channel", msgData)
+			}
+
+			// every channel, for a merged view. msgData carries the channel
+			if a.firehose.Load() {
+				a.emit("multi-message", msgData)
 			}
 
 		case reward, ok := <-conn.client.RewardChannel():
 			if !ok {
-				log.Printf("Reward channel closed for %s", conn.channel)
+				logDebugf("Reward channel closed for %s", conn.channel)
 				return
 			}
 
@@ -361,7 +831,7 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 				"username":   reward.Username,
 				"rewardName": reward.RewardName,
 				"userInput":  reward.UserInput,
-				"timestamp":  reward.Timestamp.Format("15:04:05"),
+				"timestamp":  a.formatTimestamp(reward.Timestamp),
 				"rawData":    reward.RawData,
 				"channel":    conn.channel,
 			}
@@ -372,22 +842,128 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 			a.connectionsMu.RUnlock()
 
 			if isActive {
-				runtime.EventsEmit(a.ctx, "reward-redemption", rewardData)
+				a.emit("reward-redemption", rewardData)
+			}
+			if a.firehose.Load() {
+				a.emit("multi-reward-redemption", rewardData)
+			}
+			a.logReward(conn.channel, reward)
+
+		case sets, ok := <-conn.client.EmoteSetsChannel():
+			if !ok {
+				return
+			}
+			a.updateUserEmotes(sets)
+
+		case whisper, ok := <-conn.client.WhisperChannel():
+			if !ok {
+				return
+			}
+			// every authenticated connection gets its own copy
+			if a.addWhisper(whisper) {
+				a.emit("whisper", whisper)
 			}
 
+		case notice, ok := <-conn.client.NoticeChannel():
+			if !ok {
+				logDebugf("Notice channel closed for %s", conn.channel)
+				return
+			}
+
+			logDebugf("NOTICE for %s [%s]: %s", conn.channel, notice.MsgID, notice.Content)
+
+			a.connectionsMu.RLock()
+			isActive := (a.activeChannel == conn.channel)
+			a.connectionsMu.RUnlock()
+
+			if isActive {
+				a.emit("notice", map[string]interface{}{
+					"channel":   conn.channel,
+					"msgId":     notice.MsgID,
+					"content":   notice.Content,
+					"timestamp": a.formatTimestamp(notice.Timestamp),
+				})
+			}
+
+		case presence, ok := <-conn.client.PresenceChannel():
+			if !ok {
+				return
+			}
+			event := "user-left"
+			if presence.Joined {
+				event = "user-joined"
+			}
+			a.emit(event, map[string]interface{}{
+				"channel":  conn.channel,
+				"username": presence.Username,
+			})
+
+		case action, ok := <-conn.client.ModActionChannel():
+			if !ok {
+				return
+			}
+			a.logModAction(action)
+
+		case raw, ok := <-conn.client.RawChannel():
+			if !ok {
+				return
+			}
+			if !conn.replay {
+				a.logRawIRC(raw)
+			}
+			a.emit("raw-irc", map[string]interface{}{
+				"channel":   raw.Channel,
+				"line":      raw.Line,
+				"outgoing":  raw.Outgoing,
+				"timestamp": raw.Timestamp.UnixMilli(),
+			})
+
+		case <-conn.client.ReadyChannel():
+			a.emit("channel-ready", conn.channel)
+
 		case err, ok := <-conn.client.ErrorChannel():
 			if !ok {
-				log.Printf("Error channel closed for %s", conn.channel)
+				logDebugf("Error channel closed for %s", conn.channel)
 				return
 			}
 
-			log.Printf("Twitch client error for %s: %v", conn.channel, err)
-			runtime.EventsEmit(a.ctx, "connection-error", map[string]interface{}{
-				"channel": conn.channel,
-				"error":   err.Error(),
+			logWarnf("Twitch client error for %s: %v", conn.channel, err)
+			conn.mu.Lock()
+			conn.lastErr = err
+			conn.lastErrAt = time.Now()
+			conn.mu.Unlock()
+
+			// Reconnecting won't help if Twitch refused the JOIN
+			var joinErr *JoinError
+			retrying := !errors.As(err, &joinErr)
+			if !retrying {
+				a.connectionsMu.Lock()
+				a.joinErrors[conn.channel] = channelError{err: err, at: conn.lastErrAt}
+				a.connectionsMu.Unlock()
+			}
+
+			a.emit("connection-error", map[string]interface{}{
+				"channel":  conn.channel,
+				"error":    err.Error(),
+				"retrying": retrying,
 			})
 			a.DisconnectFromChannel(conn.channel)
-			a.ConnectToChannel(conn.channel)
+
+			if !retrying {
+				return
+			}
+			if a.ConnectToChannel(conn.channel) == nil {
+				// keep the reason around on the new connection
+				a.connectionsMu.RLock()
+				newConn, exists := a.connections[conn.channel]
+				a.connectionsMu.RUnlock()
+				if exists {
+					newConn.mu.Lock()
+					newConn.lastErr = err
+					newConn.lastErrAt = conn.lastErrAt
+					newConn.mu.Unlock()
+				}
+			}
 			return
 		}
 	}
@@ -395,7 +971,7 @@ func (a *App) forwardMessages(ctx context.Context, conn *ChannelConnection) {
 
 // monitorViewerCount monitors viewer count for a specific channel
 func (a *App) monitorViewerCount(ctx context.Context, conn *ChannelConnection) {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(viewerPollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -403,19 +979,28 @@ func (a *App) monitorViewerCount(ctx context.Context, conn *ChannelConnection) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			count, err := a.GetViewerCount(conn.channel)
+			// always asks GQL, this is what keeps the stream info cache fresh
+			info, err := a.fetchStreamInfo(conn.channel, 0)
+			count := info.Viewers
 			if err == nil {
 				conn.mu.Lock()
 				conn.viewerCount = count
+				conn.viewers.Add(ViewerSample{Timestamp: time.Now().UnixMilli(), Count: count})
 				conn.mu.Unlock()
 
+				// Sidebar counts, uses the same unprefixed name as channel-live-status
+				a.emit("channel-viewer-count", map[string]interface{}{
+					"channel": strings.TrimPrefix(conn.channel, "#"),
+					"count":   count,
+				})
+
 				// Only emit if this is the active channel
 				a.connectionsMu.RLock()
 				isActive := (a.activeChannel == conn.channel)
 				a.connectionsMu.RUnlock()
 
 				if isActive {
-					runtime.EventsEmit(a.ctx, "viewer-count", count)
+					a.emit("viewer-count", count)
 				}
 			}
 		}
@@ -427,15 +1012,43 @@ func (a *App) SwitchToChannel(channel string) error {
 		channel = "#" + channel
 	}
 
-	a.connectionsMu.Lock()
+	a.connectionsMu.RLock()
+	conn, exists := a.connections[channel]
+	connected := exists && conn.isConnected
+	a.connectionsMu.RUnlock()
 
 	// Connect if it doesnt exist/disconnected
+	if !connected {
+		return a.ConnectToChannel(channel)
+	}
+	if err := a.SetActiveChannel(channel); err != nil {
+		return err
+	}
+
+	a.audioMu.Lock()
+	locked := a.audioLocked
+	a.audioMu.Unlock()
+	if a.config.AudioFollowsActive && !locked {
+		// the status check is an HTTP call, don't hold up the switch for it
+		go a.followWithAudio(strings.TrimPrefix(channel, "#"))
+	}
+
+	return nil
+}
+
+// SetActiveChannel shows an already connected channel. Unlike
+// SwitchToChannel it never connects and leaves stream audio alone.
+func (a *App) SetActiveChannel(channel string) error {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	a.connectionsMu.Lock()
 	conn, exists := a.connections[channel]
 	if !exists || !conn.isConnected {
 		a.connectionsMu.Unlock()
-		return a.ConnectToChannel(channel)
+		return fmt.Errorf("not connected to channel: %s", channel)
 	}
-
 	a.activeChannel = channel
 	a.connectionsMu.Unlock()
 
@@ -445,44 +1058,201 @@ func (a *App) SwitchToChannel(channel string) error {
 	viewerCount := conn.viewerCount
 	conn.mu.RUnlock()
 
-	if !audioLocked {
-		if audioMuted {
-			audioRecorder.StopAudio()
-		}
-		audioRecorder.channel = strings.TrimPrefix(channel, "#")
-		isLive := a.checkStreamStatus(strings.TrimPrefix(channel, "#"))
-		if !audioMuted && isLive {
-			go func() {
-				audioRecorder.StopAudio()
-				audioRecorder.StartAudioOnly(10)
-			}()
-		}
+	a.emit("viewer-count", viewerCount)
+	a.emit("channel-switched", channel)
+	return nil
+}
+
+// ensureChatFlowing reconnects channel's chat unless it's healthy. The
+// connection can quietly die while a channel sits offline.
+func (a *App) ensureChatFlowing(channel string) {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	a.connectionsMu.RLock()
+	conn, exists := a.connections[channel]
+	a.connectionsMu.RUnlock()
+	if !exists || conn.replay || conn.client.Healthy() {
+		return
 	}
 
-	runtime.EventsEmit(a.ctx, "viewer-count", viewerCount)
-	runtime.EventsEmit(a.ctx, "channel-switched", channel)
+	logWarnf("%s went live but its chat looks stale, reconnecting", channel)
+	if err := a.ReconnectChannel(channel); err != nil {
+		logWarnf("Reconnecting %s failed: %v", channel, err)
+	}
+}
 
-	return nil
+// followWithAudio moves stream audio over to channel if it's live, unless
+// audio is muted overall or for that channel
+func (a *App) followWithAudio(channel string) {
+	a.audioMu.Lock()
+	muted := a.audioMuted || a.channelAudioMuted(channel)
+	if muted {
+		a.audio.StopAudio()
+	}
+	a.audio.channel = channel
+	a.audioMu.Unlock()
+	a.emitAudioState()
+
+	isLive := a.checkStreamStatus(channel)
+	if !muted && isLive {
+		a.audio.StopAudio()
+		a.audio.StartAudioOnly(10)
+		a.emitAudioState()
+	}
 }
 
 func (a *App) ToggleAudioMute() bool {
-	audioMuted = !audioMuted
-	if audioMuted {
-		audioRecorder.StopAudio()
-	} else {
+	a.audioMu.Lock()
+	a.audioMuted = !a.audioMuted
+	muted, channel := a.audioMuted, a.audio.channel
+	a.audioMu.Unlock()
+
+	if muted {
+		a.audio.StopAudio()
+	} else if channel != "" && channel != "none" && !a.channelAudioMuted(channel) {
 		// Restart audio for current audio channel (respects lock)
-		if audioRecorder.channel != "" && audioRecorder.channel != "none" {
-			channel := audioRecorder.channel
+		go func() {
 			if a.checkStreamStatus(channel) {
-				go audioRecorder.StartAudioOnly(10)
+				a.audio.StartAudioOnly(10)
+				a.emitAudioState()
 			}
-		}
+		}()
 	}
-	return audioMuted
+	a.emitAudioState()
+	return muted
 }
 
 func (a *App) SetAudioLock(locked bool) {
-	audioLocked = locked
+	a.audioMu.Lock()
+	a.audioLocked = locked
+	a.audioMu.Unlock()
+	a.emitAudioState()
+}
+
+type AudioState struct {
+	Channel      string `json:"channel"`
+	Muted        bool   `json:"muted"`        // everything, overrides ChannelMuted
+	ChannelMuted bool   `json:"channelMuted"` // remembered for Channel
+	Locked       bool   `json:"locked"`
+	Playing      bool   `json:"playing"`
+}
+
+// GetAudioState reports which channel stream audio is on and whether it's
+// playing. Channel is "" until one has been picked.
+func (a *App) GetAudioState() AudioState {
+	a.audioMu.Lock()
+	state := AudioState{
+		Channel: a.audio.channel,
+		Muted:   a.audioMuted,
+		Locked:  a.audioLocked,
+	}
+	a.audioMu.Unlock()
+	if state.Channel == "none" {
+		state.Channel = ""
+	}
+	state.ChannelMuted = state.Channel != "" && a.channelAudioMuted(state.Channel)
+	state.Playing = a.audio.Playing()
+	return state
+}
+
+// channelAudioMuted reports whether stream audio was last muted for channel
+func (a *App) channelAudioMuted(channel string) bool {
+	return a.getPreferences().MutedChannels[strings.TrimPrefix(channel, "#")]
+}
+
+// SetChannelAudioMuted remembers whether channel's stream audio should play
+// when it's switched to, and applies it right away if audio is on that
+// channel. The overall mute (ToggleAudioMute) still wins.
+func (a *App) SetChannelAudioMuted(channel string, muted bool) error {
+	login, err := normalizeChannelName(channel)
+	if err != nil {
+		return err
+	}
+	// copy on write, getPreferences hands out the map without the lock
+	a.updatePreferences(func(p *Preferences) {
+		mutedChannels := maps.Clone(p.MutedChannels)
+		if mutedChannels == nil {
+			mutedChannels = make(map[string]bool)
+		}
+		if muted {
+			mutedChannels[login] = true
+		} else {
+			delete(mutedChannels, login)
+		}
+		p.MutedChannels = mutedChannels
+	})
+
+	a.audioMu.Lock()
+	current := a.audio.channel == login
+	globallyMuted := a.audioMuted
+	a.audioMu.Unlock()
+	if !current {
+		a.emitAudioState()
+		return nil
+	}
+
+	if muted {
+		a.audio.StopAudio()
+	} else if !globallyMuted && !a.audio.Playing() {
+		go func() {
+			if a.checkStreamStatus(login) {
+				a.audio.StartAudioOnly(10)
+				a.emitAudioState()
+			}
+		}()
+	}
+	a.emitAudioState()
+	return nil
+}
+
+func (a *App) emitAudioState() {
+	if a.ctx == nil {
+		return
+	}
+	a.emit("audio-state", a.GetAudioState())
+}
+
+// SetAlertsMuted silences live TTS alerts and highlight dings
+func (a *App) SetAlertsMuted(muted bool) {
+	a.updatePreferences(func(p *Preferences) { p.AlertsMuted = muted })
+}
+
+func (a *App) GetAlertsMuted() bool {
+	return a.getPreferences().AlertsMuted
+}
+
+// AudioAvailable reports whether an audio device was found for alerts/TTS
+func (a *App) AudioAvailable() bool {
+	return a.audioOut != nil
+}
+
+// TTSAvailable reports whether piper and its voice model were found
+func (a *App) TTSAvailable() bool {
+	return a.ttsAvailable
+}
+
+// PreviewTTS speaks text with the configured voice right away, so it can be
+// checked without waiting for a channel to go live. Nothing is cached.
+func (a *App) PreviewTTS(text string) error {
+	if a.audioOut == nil {
+		return fmt.Errorf("audio output unavailable: %v", a.audioOutErr)
+	}
+	if strings.TrimSpace(text) == "" {
+		// same default as generate_tts.bat
+		message := a.config.TTSMessage
+		if message == "" {
+			message = "is now streaming."
+		}
+		text = "channel " + message
+	}
+
+	wav, err := a.synthesizeTTS(text)
+	if err != nil {
+		return err
+	}
+	go a.playWav(wav, 0.10)
+	return nil
 }
 
 func (a *App) emitRecentMessages(channel string) {
@@ -496,14 +1266,14 @@ func (a *App) emitRecentMessages(channel string) {
 	copy(messages, conn.messages)
 	conn.mu.RUnlock()
 
-	runtime.EventsEmit(a.ctx, "channel-messages", map[string]interface{}{
+	a.emit("channel-messages", map[string]interface{}{
 		"channel":  channel,
 		"messages": messages,
 	})
 }
 
 func (a *App) DisconnectFromChannel(channel string) error {
-	log.Printf("DisconnectFromChannel called for: %s", channel)
+	logDebugf("DisconnectFromChannel called for: %s", channel)
 
 	if !strings.HasPrefix(channel, "#") {
 		channel = "#" + channel
@@ -514,34 +1284,105 @@ func (a *App) DisconnectFromChannel(channel string) error {
 
 	conn, exists := a.connections[channel]
 	if !exists {
-		log.Printf("Channel %s not found in connections", channel)
+		logWarnf("Channel %s not found in connections", channel)
 		return fmt.Errorf("not connected to channel: %s", channel)
 	}
 
-	log.Printf("Stopping connection for %s...", channel)
+	logDebugf("Stopping connection for %s...", channel)
 
 	if conn.cancel != nil {
-		log.Printf("Cancelling context for %s", channel)
+		logDebugf("Cancelling context for %s", channel)
 		conn.cancel()
 	}
 
-	if conn.client != nil {
-		log.Printf("Stopping client for %s", channel)
-		conn.client.Stop()
+	if conn.client != nil {
+		logDebugf("Stopping client for %s", channel)
+		conn.client.Stop()
+	}
+
+	conn.isConnected = false
+	delete(a.connections, channel)
+	logDebugf("Removed %s from connections map", channel)
+
+	if a.activeChannel == channel {
+		logDebugf("%s was active channel, clearing active channel", channel)
+		a.activeChannel = ""
+		a.emit("active-channel-disconnected", channel)
+	}
+
+	logDebugf("Successfully disconnected from %s", channel)
+	a.emit("channel-disconnected", channel)
+	return nil
+}
+
+// ReconnectChannel drops and redials a single channel, e.g. when it has gone
+// quiet. The message buffer and active channel carry over to the new
+// connection and the buffer is re-emitted.
+func (a *App) ReconnectChannel(channel string) error {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	snap, exists := a.snapshotChannel(channel)
+	if !exists {
+		return fmt.Errorf("not connected to channel: %s", channel)
+	}
+
+	if err := a.DisconnectFromChannel(channel); err != nil {
+		return err
+	}
+	if err := a.ConnectToChannel(channel); err != nil {
+		return err
+	}
+	return a.restoreChannel(channel, snap)
+}
+
+// channelSnapshot is what carries over when a channel is reconnected
+type channelSnapshot struct {
+	messages []map[string]interface{}
+	paused   bool
+	active   bool
+}
+
+func (a *App) snapshotChannel(channel string) (channelSnapshot, bool) {
+	a.connectionsMu.RLock()
+	old, exists := a.connections[channel]
+	wasActive := a.activeChannel == channel
+	a.connectionsMu.RUnlock()
+	if !exists {
+		return channelSnapshot{}, false
+	}
+
+	old.mu.RLock()
+	defer old.mu.RUnlock()
+	return channelSnapshot{messages: old.messages, paused: old.paused, active: wasActive}, true
+}
+
+// restoreChannel puts snap back on channel's new connection and re-emits
+// the buffer
+func (a *App) restoreChannel(channel string, snap channelSnapshot) error {
+	a.connectionsMu.Lock()
+	defer a.connectionsMu.Unlock()
+	conn, exists := a.connections[channel]
+	if !exists {
+		return fmt.Errorf("%s went away while reconnecting", channel)
+	}
+
+	conn.mu.Lock()
+	// anything that arrived in between goes after the old buffer
+	merged := append(snap.messages, conn.messages...)
+	if len(merged) > conn.bufferSize {
+		merged = merged[len(merged)-conn.bufferSize:]
 	}
+	conn.messages = merged
+	conn.paused = snap.paused
+	conn.mu.Unlock()
 
-	conn.isConnected = false
-	delete(a.connections, channel)
-	log.Printf("Removed %s from connections map", channel)
-
-	if a.activeChannel == channel {
-		log.Printf("%s was active channel, clearing active channel", channel)
-		a.activeChannel = ""
-		runtime.EventsEmit(a.ctx, "active-channel-disconnected", channel)
+	if snap.active {
+		a.activeChannel = channel
+		a.emit("channel-switched", channel)
 	}
-
-	log.Printf("Successfully disconnected from %s", channel)
-	runtime.EventsEmit(a.ctx, "channel-disconnected", channel)
+	a.emitRecentMessages(channel)
 	return nil
 }
 
@@ -557,12 +1398,12 @@ func (a *App) DisconnectFromAllChannels() {
 		if conn.client != nil {
 			conn.client.Stop()
 		}
-		log.Printf("Disconnected from %s", channel)
+		logInfof("Disconnected from %s", channel)
 	}
 
 	a.connections = make(map[string]*ChannelConnection)
 	a.activeChannel = ""
-	runtime.EventsEmit(a.ctx, "all-channels-disconnected", nil)
+	a.emit("all-channels-disconnected", nil)
 }
 
 // Unused atm
@@ -604,6 +1445,65 @@ func (a *App) GetRecentMessages(channel string, count int) []map[string]interfac
 	return conn.messages[start:]
 }
 
+// ExportChannelBuffer writes the channel's in-memory messages to a file in
+// the exports directory and returns its path. format is "text" or "json".
+func (a *App) ExportChannelBuffer(channel, format string) (string, error) {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	a.connectionsMu.RLock()
+	conn, exists := a.connections[channel]
+	a.connectionsMu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("not connected to channel: %s", channel)
+	}
+
+	conn.mu.RLock()
+	messages := make([]map[string]interface{}, len(conn.messages))
+	copy(messages, conn.messages)
+	conn.mu.RUnlock()
+
+	if len(messages) == 0 {
+		return "", fmt.Errorf("no messages buffered for %s", channel)
+	}
+
+	var data []byte
+	ext := "txt"
+	switch strings.ToLower(format) {
+	case "json":
+		encoded, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("encoding messages: %w", err)
+		}
+		data = encoded
+		ext = "json"
+	case "text", "txt", "":
+		var sb strings.Builder
+		for _, m := range messages {
+			fmt.Fprintf(&sb, "[%v] %v: %v\n", m["timestamp"], m["username"], m["content"])
+		}
+		data = []byte(sb.String())
+	default:
+		return "", fmt.Errorf("unknown export format: %s", format)
+	}
+
+	dir := a.dataPath("exports")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating export directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s.%s", strings.TrimPrefix(channel, "#"), time.Now().Format("2006-01-02_15-04-05"), ext)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("writing export: %w", err)
+	}
+
+	logInfof("Exported %d messages from %s to %s", len(messages), channel, path)
+	return path, nil
+}
+
 func (a *App) GetChannels() []string {
 	a.connectionsMu.RLock()
 	defer a.connectionsMu.RUnlock()
@@ -623,12 +1523,15 @@ func (a *App) AddChannel(channel string) {
 	// TTS
 	isLive := a.checkStreamStatus(channel)
 	if isLive {
-		mp3File := getMp3ForChannel(channel)
-		go playWav(otoCtx, mp3File, 0.10)
-		log.Println("Starting archiving for ", channel)
+		settings := a.channelSettings(channel)
+		if settings.TTS {
+			mp3File := a.getMp3ForChannel(channel)
+			go a.playAlert(mp3File, 0.10)
+		}
+		logDebugf("Starting archiving for %s", channel)
 		go func(ch string) {
-			if toRecord {
-				recorder := NewTwitchRecorder(ch, archiveDir)
+			if a.recording && settings.Record {
+				recorder := a.newRecorder(ch)
 				recorder.Start()
 			}
 		}(channel)
@@ -640,50 +1543,134 @@ func (a *App) AddChannel(channel string) {
 
 	a.ConnectToChannel(channel)
 
-	runtime.EventsEmit(a.ctx, "channel-live-status", map[string]interface{}{
+	a.emit("channel-live-status", map[string]interface{}{
 		"channel": channel,
 		"isLive":  isLive,
 	})
 }
 
 func (a *App) RemoveChannel(channel string) {
-	log.Printf("RemoveChannel called for: %s", channel)
+	logDebugf("RemoveChannel called for: %s", channel)
 
 	normalizedChannel := channel
 	if !strings.HasPrefix(channel, "#") {
 		normalizedChannel = "#" + channel
 	}
 
-	log.Printf("Disconnecting from channel if connected...")
+	logDebugf("Disconnecting from channel if connected...")
 	if err := a.DisconnectFromChannel(normalizedChannel); err != nil {
-		log.Printf("Error disconnecting from %s: %v", normalizedChannel, err)
+		logWarnf("Error disconnecting from %s: %v", normalizedChannel, err)
 	}
 
-	log.Printf("Removing from channels list...")
+	logDebugf("Removing from channels list...")
 
 	originalChannelCount := len(a.channels)
 
 	for i, ch := range a.channels {
 		if ch == channel {
-			log.Printf("Found channel %s at index %d, removing...", channel, i)
+			logDebugf("Found channel %s at index %d, removing...", channel, i)
 			a.channels = append(a.channels[:i], a.channels[i+1:]...)
 			break
 		}
 	}
 
 	newChannelCount := len(a.channels)
-	log.Printf("Channel count: %d -> %d", originalChannelCount, newChannelCount)
+	logDebugf("Channel count: %d -> %d", originalChannelCount, newChannelCount)
 
 	a.connectionsMu.Lock()
 	if _, exists := a.liveStatuses[channel]; exists {
 		delete(a.liveStatuses, channel)
-		log.Printf("Cleaned up live status for %s", channel)
+		logDebugf("Cleaned up live status for %s", channel)
 	}
+	delete(a.joinErrors, normalizedChannel)
 	a.connectionsMu.Unlock()
 
-	log.Printf("Successfully removed channel: %s", channel)
+	logDebugf("Successfully removed channel: %s", channel)
+
+	a.emit("channel-removed", channel)
+}
+
+// SendMessage sends a chat message through the channel's IRC connection
+func (a *App) SendMessage(channel, message string) error {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	a.connectionsMu.RLock()
+	conn, exists := a.connections[channel]
+	a.connectionsMu.RUnlock()
+
+	if !exists || conn.client == nil {
+		return fmt.Errorf("not connected to channel: %s", channel)
+	}
+	return conn.client.SendMessage(message)
+}
+
+// SetRawIRC turns the raw IRC debug stream on or off: every line sent or
+// received is emitted as "raw-irc" and written to logs/<date>_rawirc.txt.
+// Tokens are redacted.
+func (a *App) SetRawIRC(enabled bool) {
+	a.rawIRC.Store(enabled)
+}
+
+func (a *App) GetRawIRC() bool {
+	return a.rawIRC.Load()
+}
+
+// SetFirehose turns the multi-message and multi-reward-redemption events
+// (everything from every connected channel) on or off. It's high volume, off unless $firehose=true.
+func (a *App) SetFirehose(enabled bool) {
+	a.firehose.Store(enabled)
+}
+
+func (a *App) GetFirehose() bool {
+	return a.firehose.Load()
+}
+
+// PauseChannel stops new-message events for a channel without disconnecting.
+// Messages are still buffered and logged.
+func (a *App) PauseChannel(channel string) error {
+	return a.setPaused(channel, true)
+}
+
+// ResumeChannel undoes PauseChannel and re-sends the buffer, which includes
+// everything that arrived while paused (up to the buffer size)
+func (a *App) ResumeChannel(channel string) error {
+	if err := a.setPaused(channel, false); err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+	if a.GetActiveChannel() == channel {
+		a.emitRecentMessages(channel)
+	}
+	return nil
+}
+
+func (a *App) setPaused(channel string, paused bool) error {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	a.connectionsMu.RLock()
+	conn, exists := a.connections[channel]
+	a.connectionsMu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("not connected to channel: %s", channel)
+	}
+
+	conn.mu.Lock()
+	conn.paused = paused
+	conn.mu.Unlock()
 
-	runtime.EventsEmit(a.ctx, "channel-removed", channel)
+	a.emit("channel-paused", map[string]interface{}{
+		"channel": channel,
+		"paused":  paused,
+	})
+	return nil
 }
 
 func (a *App) GetActiveChannel() string {
@@ -708,13 +1695,68 @@ func (a *App) GetCurrentViewerCount() int {
 	return 0
 }
 
+// GetViewerHistory returns the recent viewer counts for a channel, oldest first
+func (a *App) GetViewerHistory(channel string) []ViewerSample {
+	if !strings.HasPrefix(channel, "#") {
+		channel = "#" + channel
+	}
+
+	a.connectionsMu.RLock()
+	conn, exists := a.connections[channel]
+	a.connectionsMu.RUnlock()
+
+	if !exists {
+		return []ViewerSample{}
+	}
+
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	return conn.viewers.Samples()
+}
+
+// messageEmotes is what the frontend needs to draw a message's emotes
+type messageEmotes struct {
+	images  map[string]string            // name -> data URL
+	sources map[string]map[string]string // name -> provider/scope, for tooltips
+	sizes   map[string]map[string]int    // name -> width/height, to reserve space
+	missing []string                     // no usable image, shown as text
+}
+
+// encodeMessageEmotes loads the images for a message's emotes. Emotes
+// without a usable image (still downloading, failed or deleted) are listed
+// in missing so they stay in the message as text.
+func (a *App) encodeMessageEmotes(emotes []EmoteInfo, msg *Message) messageEmotes {
+	rendered := messageEmotes{
+		images:  make(map[string]string),
+		sources: make(map[string]map[string]string),
+		sizes:   make(map[string]map[string]int),
+		missing: make([]string, 0),
+	}
+	for _, emote := range emotes {
+		base64, err := a.GetEmoteBase64(emote.FilePath, emote, msg)
+		if err != nil {
+			logDebugf("Error encoding emote: %v", err)
+			if !slices.Contains(rendered.missing, emote.Name) {
+				rendered.missing = append(rendered.missing, emote.Name)
+			}
+			continue
+		}
+		rendered.images[emote.Name] = base64
+		rendered.sources[emote.Name] = map[string]string{"provider": emote.Provider, "scope": emote.Scope}
+		if emote.Width > 0 {
+			rendered.sizes[emote.Name] = map[string]int{"width": emote.Width, "height": emote.Height}
+		}
+	}
+	return rendered
+}
+
 func (a *App) GetEmoteBase64(filePath string, emote EmoteInfo, msg *Message) (string, error) {
 	// log.Println("get emote for", filePath, "\nemote: ", emote)
 
 	if strings.HasPrefix(emote.URL, "https://static-cdn.jtvnw.net") {
 		// return filepath.ToSlash(emote.FilePath), nil
 		tmp := fmt.Sprintf("%s_%s.png", emote.Name, emote.ID)
-		filePath = filepath.Join("channels", strings.TrimPrefix(msg.Channel, "#"), "emotes", tmp)
+		filePath = a.dataPath("channels", strings.TrimPrefix(msg.Channel, "#"), "emotes", tmp)
 	}
 
 	data, err := os.ReadFile(filePath)
@@ -732,87 +1774,108 @@ func (a *App) GetEmoteBase64(filePath string, emote EmoteInfo, msg *Message) (st
 	return fmt.Sprintf("data:%s;base64,%s", contentType, encoded), nil
 }
 
-func (a *App) GetViewerCount(channel string) (int, error) {
-	channel = strings.TrimPrefix(channel, "#")
+// StreamInfo is the live status and viewer count from one GQL query
+type StreamInfo struct {
+	Live    bool
+	Viewers int
+}
 
-	url := "https://gql.twitch.tv/gql"
-	query := fmt.Sprintf(`{"query":"query { user(login:\"%s\") { stream { viewersCount } } }"}`, channel)
+// The viewer poll refreshes connected channels every viewerPollInterval
+// and the live status check reads what it left, so those channels are only
+// asked about once. Two polls' worth in case one of them failed.
+const streamInfoMaxAge = 2 * viewerPollInterval
 
-	req, err := http.NewRequest("POST", url, strings.NewReader(query))
-	if err != nil {
-		return 0, err
-	}
+type cachedStreamInfo struct {
+	info    StreamInfo
+	fetched time.Time
+}
 
-	req.Header.Set("Client-ID", "kimne78kx3ncx6brgo4mv6wki5h1ko")
-	req.Header.Set("Content-Type", "application/json")
+// Stream info by login, shared by the viewer count and live status checks
+type streamInfoCache struct {
+	sync.Mutex
+	entries map[string]cachedStreamInfo
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, err
+// fetchStreamInfo asks GQL whether channel is live and how many are watching,
+// unless it was asked less than maxAge ago
+func (a *App) fetchStreamInfo(channel string, maxAge time.Duration) (StreamInfo, error) {
+	channel = strings.TrimPrefix(channel, "#")
+
+	a.streamInfo.Lock()
+	cached, ok := a.streamInfo.entries[channel]
+	a.streamInfo.Unlock()
+	if ok && time.Since(cached.fetched) < maxAge {
+		return cached.info, nil
 	}
-	defer resp.Body.Close()
+
+	query := fmt.Sprintf(`{"query":"query { user(login:\"%s\") { stream { id viewersCount } } }"}`, channel)
 
 	var result struct {
 		Data struct {
 			User struct {
-				Stream struct {
-					ViewersCount int `json:"viewersCount"`
+				Stream *struct {
+					ID           string `json:"id"`
+					ViewersCount int    `json:"viewersCount"`
 				} `json:"stream"`
 			} `json:"user"`
 		} `json:"data"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, err
+	if err := a.gqlRequest(query, &result); err != nil {
+		return StreamInfo{}, err
 	}
 
-	return result.Data.User.Stream.ViewersCount, nil
-}
-
-func (a *App) checkStreamStatus(channel string) bool {
-	channel = strings.TrimPrefix(channel, "#")
-	url := "https://gql.twitch.tv/gql"
-	query := fmt.Sprintf(`{"query":"query { user(login:\"%s\") { stream { id } } }"}`, channel)
-
-	req, err := http.NewRequest("POST", url, strings.NewReader(query))
-	if err != nil {
-		log.Printf("Error creating request for %s: %v", channel, err)
-		return false
+	var info StreamInfo
+	if stream := result.Data.User.Stream; stream != nil {
+		info = StreamInfo{Live: true, Viewers: stream.ViewersCount}
 	}
 
-	req.Header.Set("Client-ID", "kimne78kx3ncx6brgo4mv6wki5h1ko")
-	req.Header.Set("Content-Type", "application/json")
+	a.streamInfo.Lock()
+	a.streamInfo.entries[channel] = cachedStreamInfo{info: info, fetched: time.Now()}
+	a.streamInfo.Unlock()
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	return info, nil
+}
+
+// GetViewerCount returns the current viewer count, 0 when offline
+func (a *App) GetViewerCount(channel string) (int, error) {
+	info, err := a.fetchStreamInfo(channel, streamInfoMaxAge)
+	if err != nil {
+		return 0, err
 	}
+	return info.Viewers, nil
+}
 
-	resp, err := client.Do(req)
+func (a *App) checkStreamStatus(channel string) bool {
+	isLive, err := a.streamStatus(channel)
 	if err != nil {
-		log.Printf("Error checking stream status for %s: %v", channel, err)
-		return false
+		logWarnf("Error checking stream status for %s: %v", channel, err)
 	}
-	defer resp.Body.Close()
+	return isLive
+}
 
-	var result struct {
-		Data struct {
-			User struct {
-				Stream *struct {
-					ID string `json:"id"`
-				} `json:"stream"`
-			} `json:"user"`
-		} `json:"data"`
+// streamStatus is checkStreamStatus for callers that need to tell "offline"
+// apart from "couldn't check"
+func (a *App) streamStatus(channel string) (bool, error) {
+	channel = strings.TrimPrefix(channel, "#")
+	info, err := a.fetchStreamInfo(channel, streamInfoMaxAge)
+	if err != nil {
+		return false, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Printf("Error decoding response for %s: %v", channel, err)
-		return false
+	// keep the viewer count in step, it'd otherwise show a stale number
+	// until the next viewer poll
+	a.connectionsMu.RLock()
+	conn, exists := a.connections["#"+channel]
+	a.connectionsMu.RUnlock()
+	if exists {
+		conn.mu.Lock()
+		conn.viewerCount = info.Viewers
+		conn.mu.Unlock()
 	}
 
-	isLive := result.Data.User.Stream != nil
-	log.Printf("Checking %s via GraphQL -> Live: %t", channel, isLive)
-	return isLive
+	logDebugf("Checking %s via GraphQL -> Live: %t", channel, info.Live)
+	return info.Live, nil
 }
 
 // func (a *App) checkStreamStatus(channel string) bool {
@@ -840,64 +1903,68 @@ func (a *App) checkStreamStatus(channel string) bool {
 // }
 
 func (a *App) startLiveStatusMonitoring() {
-	log.Printf("Starting live status monitoring for %d channels", len(a.channels))
+	logInfof("Starting live status monitoring for %d channels", len(a.channels))
 
 	// Initial check for all channels
 	for _, channel := range a.channels {
 		// go func(ch string) {
 		isLive := a.checkStreamStatus(channel)
 		if isLive {
-			log.Printf("Initial check for channel: %s", channel)
+			logDebugf("Initial check for channel: %s", channel)
 		}
 
-		mp3File := getMp3ForChannel(channel)
+		settings := a.channelSettings(channel)
 
 		func() {
 			a.connectionsMu.Lock()
 			defer a.connectionsMu.Unlock()
 			a.liveStatuses[channel] = isLive
 		}()
+		// later polls only record changes, so the first status goes in here
+		a.recordLiveStatus(channel, isLive)
 
 		if isLive {
-			playWav(otoCtx, mp3File, 0.10)
-			log.Println("Starting archiving for ", channel)
+			if settings.TTS {
+				a.playAlert(a.getMp3ForChannel(channel), 0.10)
+			}
+			logDebugf("Starting archiving for %s", channel)
 
 			go func(ch string) {
-				if toRecord && channels_map[channel] {
-					recorder := NewTwitchRecorder(ch, archiveDir)
+				if a.recording && settings.Record {
+					recorder := a.newRecorder(ch)
 					recorder.Start()
 				}
 			}(channel)
 		}
-		runtime.EventsEmit(a.ctx, "channel-live-status", map[string]interface{}{
+		a.emit("channel-live-status", map[string]interface{}{
 			"channel": channel,
 			"isLive":  isLive,
 		})
 
-		log.Printf("Channel %s initial status: %t", channel, isLive)
+		logDebugf("Channel %s initial status: %t", channel, isLive)
 
-		time.Sleep(50 * time.Millisecond)
+		time.Sleep(a.config.PollStagger)
 		// }(channel)
 	}
 
 	// Ticker for periodic checks
-	a.statusTicker = time.NewTicker(2 * time.Minute)
+	a.statusTicker = time.NewTicker(a.config.PollInterval)
 
-	log.Printf("Live status monitoring started, checking every 2 minutes")
+	logInfof("Live status monitoring started, checking every %v", a.config.PollInterval)
 
 	for {
 		select {
 		case <-a.statusTicker.C:
-			log.Printf("Periodic live status check...")
+			logDebugf("Periodic live status check...")
 			a.checkAllChannelsStatus()
 		case <-a.stopMonitoring:
-			log.Printf("Stopping live status monitoring")
+			logInfof("Stopping live status monitoring")
 			if a.statusTicker != nil {
 				a.statusTicker.Stop()
 			}
 			return
 		case <-a.ctx.Done():
-			log.Printf("Context done, stopping live status monitoring")
+			logInfof("Context done, stopping live status monitoring")
 			if a.statusTicker != nil {
 				a.statusTicker.Stop()
 			}
@@ -909,42 +1976,69 @@ func (a *App) startLiveStatusMonitoring() {
 // Check all channels and emit updates when status changes
 func (a *App) checkAllChannelsStatus() {
 	for _, channel := range a.channels {
-		currentStatus := a.checkStreamStatus(channel)
+		currentStatus, err := a.streamStatus(channel)
+		if err != nil {
+			// keep the last known status rather than flapping to offline
+			logWarnf("Error checking stream status for %s: %v", channel, err)
+			if errors.Is(err, ErrTwitchAPIDegraded) {
+				return
+			}
+			continue
+		}
 
 		a.connectionsMu.Lock()
 		previousStatus, exists := a.liveStatuses[channel]
 
 		// If status changed or first check for this channel
 		if !exists || previousStatus != currentStatus {
-			log.Println(a.liveStatuses)
+			logDebugf("Live statuses: %v", a.liveStatuses)
 			a.liveStatuses[channel] = currentStatus
 			a.connectionsMu.Unlock()
 
 			if currentStatus {
-				// play mp3
-				mp3File := getMp3ForChannel(channel)
-				playWav(otoCtx, mp3File, 0.10)
-				log.Println("Starting archiving for ", channel)
+				if a.config.NotifyLive {
+					a.notify("live:"+channel, channel+" is live", "twitch.tv/"+channel)
+				}
+				settings := a.channelSettings(channel)
+				// only on the offline -> live transition, not for channels
+				// we haven't seen a status for yet
+				if exists && a.config.ReconnectOnLive {
+					go a.ensureChatFlowing(channel)
+				}
+				if exists && (a.config.AutoSwitch || settings.Switch) {
+					logInfof("%s went live, switching to it", channel)
+					go func(ch string) {
+						if err := a.SwitchToChannel(ch); err != nil {
+							logWarnf("Auto-switch to %s failed: %v", ch, err)
+						}
+					}(channel)
+				}
+				if settings.TTS {
+					mp3File := a.getMp3ForChannel(channel)
+					a.playAlert(mp3File, 0.10)
+				}
+				logDebugf("Starting archiving for %s", channel)
 
 				go func(ch string) {
-					if toRecord && channels_map[channel] {
-						recorder := NewTwitchRecorder(ch, archiveDir)
+					if a.recording && settings.Record {
+						recorder := a.newRecorder(ch)
 						recorder.Start()
 					}
 				}(channel)
 			}
 
-			runtime.EventsEmit(a.ctx, "channel-live-status", map[string]interface{}{
+			a.recordLiveStatus(channel, currentStatus)
+			a.emit("channel-live-status", map[string]interface{}{
 				"channel": channel,
 				"isLive":  currentStatus,
 			})
 
-			log.Printf("Channel %s status changed: %t -> %t", channel, previousStatus, currentStatus)
+			logInfof("Channel %s status changed: %t -> %t", channel, previousStatus, currentStatus)
 		} else {
 			a.connectionsMu.Unlock()
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		time.Sleep(a.config.PollStagger)
 	}
 }
 
@@ -954,30 +2048,164 @@ func (a *App) GetChannelLiveStatus(channel string) bool {
 	return a.liveStatuses[strings.TrimPrefix(channel, "#")]
 }
 
-// For future use maybe
+// emit sends an event to the frontend. Monitors and downloads run in
+// their own goroutines and may fire after startup failed or while the
+// window is closing, the runtime isn't safe to use then.
+func (a *App) emit(event string, data ...interface{}) {
+	if a.ctx == nil || a.shuttingDown.Load() {
+		return
+	}
+	runtime.EventsEmit(a.ctx, event, data...)
+}
+
+// How long closing waits for recordings to wrap up before giving up on them
+const shutdownTimeout = 3 * time.Second
+
+// OnBeforeClose stops everything that would outlive the window: chat
+// connections, the status monitor, stream audio and streamlink recordings.
+// It waits up to shutdownTimeout for the recordings, then lets the window close.
 func (a *App) OnBeforeClose(ctx context.Context) bool {
+	if !a.shuttingDown.CompareAndSwap(false, true) {
+		return false
+	}
 	a.DisconnectFromAllChannels()
 	if a.stopMonitoring != nil {
 		close(a.stopMonitoring)
 	}
+
+	a.audio.StopAudio()
+	a.cleanupStreamlinkProcs()
+
+	deadline := time.Now().Add(shutdownTimeout)
+	for a.activeRecordings.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if n := a.activeRecordings.Load(); n > 0 {
+		logWarnf("%d recordings still running after %v, closing anyway", n, shutdownTimeout)
+	}
+
+	a.closeChatLogs()
 	return false
 }
 
 func (a *App) GetBufferSize() int {
-	return bufferSize
+	return int(a.bufferSize.Load())
+}
+
+// SetBufferSize changes how many messages are kept per channel. It applies
+// to connections made from now on, e.g. after ReconnectChannel.
+func (a *App) SetBufferSize(n int) error {
+	if n < minBufferSize || n > maxBufferSize {
+		return fmt.Errorf("buffer size must be between %d and %d", minBufferSize, maxBufferSize)
+	}
+	a.bufferSize.Store(int32(n))
+	return nil
+}
+
+// GetConfigErrors lists the problems found while reading config.txt
+func (a *App) GetConfigErrors() []string {
+	if a.configErr == nil {
+		return nil
+	}
+	return strings.Split(a.configErr.Error(), "\n")
 }
 
 func (a *App) GetTwitchConfig() TwitchConfig {
-	return GetTwitchConfigFromFile("config.txt")
+	return GetTwitchConfigFromFile(a.configPath)
 }
 
-// SearchEmotes returns up to <limit> emotes whose names start with <query>
-// (case-insensitive) for the given channel.
-//
-// Priority: channel 7TV -> channel BTTV -> channel FFZ
+// authConfigured reports whether the active account can log in. Without
+// one chat is read as an anonymous justinfan user.
+func (a *App) authConfigured() bool {
+	_, account := a.activeAccount()
+	return account.valid()
+}
+
+// AuthStatus tells the UI whether we're logged in and what's unavailable
+// if not
+type AuthStatus struct {
+	Authenticated bool     `json:"authenticated"`
+	Account       string   `json:"account"`
+	Nickname      string   `json:"nickname,omitempty"`
+	Disabled      []string `json:"disabled"` // features that need $nick and $oauth
+}
+
+func (a *App) GetAuthStatus() AuthStatus {
+	name, account := a.activeAccount()
+	status := AuthStatus{Authenticated: account.valid(), Account: name, Disabled: a.authDisabledFeatures()}
+	if status.Authenticated {
+		status.Nickname = account.Nick
+	}
+	return status
+}
+
+func (a *App) authDisabledFeatures() []string {
+	if a.authConfigured() {
+		return []string{}
+	}
+	disabled := []string{"sending messages", "whispers"}
+	if a.config.ModLog {
+		disabled = append(disabled, "mod log")
+	}
+	return disabled
+}
+
+// GetChannelAliases returns the display names set with alias:login=name,
+// keyed by login. Logins without an alias aren't included.
+func (a *App) GetChannelAliases() map[string]string {
+	aliases := make(map[string]string, len(a.config.Aliases))
+	for login, alias := range a.config.Aliases {
+		aliases[login] = alias
+	}
+	return aliases
+}
+
+// GetChannelDisplayName is the alias for channel, or its login if it has none
+func (a *App) GetChannelDisplayName(channel string) string {
+	login := strings.TrimPrefix(channel, "#")
+	if alias, ok := a.config.Aliases[login]; ok {
+		return alias
+	}
+	return login
+}
+
+// GetEmoteCacheSize reports the bytes of downloaded emotes per channel,
+// "global" included
+func (a *App) GetEmoteCacheSize() (map[string]int64, error) {
+	return a.emoteDiskUsage()
+}
+
+// ClearEmoteCache deletes a channel's downloaded emotes. They come back the
+// next time the channel's emotes are fetched. The shared global set is
+// only removed through ClearGlobalEmoteCache.
+func (a *App) ClearEmoteCache(channel string) error {
+	login, err := normalizeChannelName(channel)
+	if err != nil {
+		return err
+	}
+	if login == "global" {
+		return fmt.Errorf("refusing to clear the global emotes, use ClearGlobalEmoteCache")
+	}
+	return a.clearChannelEmotes(login)
+}
+
+// ClearGlobalEmoteCache deletes the global 7TV/BTTV/FFZ emotes
+func (a *App) ClearGlobalEmoteCache() error {
+	return a.clearGlobalEmotes()
+}
+
+// GetChannelEmotes lists the 7TV/BTTV/FFZ emotes (channel and global) that
+// work in a channel, for the emote picker. FilePath is the image to load.
+func (a *App) GetChannelEmotes(channel string) []EmoteInfo {
+	return a.listEmotes(channel)
+}
+
+// SearchEmotes returns up to <limit> emotes for the given channel whose
+// names start with <query> (case-insensitive), then ones containing it.
 //
-//	global 7TV -> global BTTV -> global FFZ
-//	disk fallback (scans emotes_7tv / emotes_bttv / emotes_ffz dirs)
+// Sets are searched in emoteLookupOrder, the order chat uses, so a name
+// that's in several sets shows the same image as in chat. Emotes cached on
+// disk but not loaded yet come after, same order, then twitch.
 func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchResult {
 	channelName = strings.TrimPrefix(channelName, "#")
 	query = strings.ToLower(query)
@@ -986,7 +2214,7 @@ func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchRe
 	}
 
 	seen := make(map[string]bool)
-	var results []EmoteSearchResult
+	var results, contained []EmoteSearchResult // prefix matches, other matches
 
 	matches := func(name string) bool {
 		if query == "" {
@@ -996,7 +2224,7 @@ func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchRe
 	}
 
 	// Returns false when the limit is reached (caller should stop iterating).
-	add := func(name, filePath, source string) bool {
+	add := func(name, filePath, provider, scope string) bool {
 		if len(results) >= limit {
 			return false
 		}
@@ -1004,7 +2232,23 @@ func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchRe
 			return true // skip but keep going
 		}
 		seen[name] = true
-		results = append(results, EmoteSearchResult{Name: name, FilePath: filePath, Source: source})
+		scope = cmp.Or(scope, "channel")
+		source := provider
+		if scope == "global" {
+			source += "-global"
+		}
+		result := EmoteSearchResult{
+			Name:     name,
+			FilePath: filePath,
+			Source:   source,
+			Provider: provider,
+			Scope:    scope,
+		}
+		if strings.HasPrefix(strings.ToLower(name), query) {
+			results = append(results, result)
+		} else if len(contained) < limit {
+			contained = append(contained, result)
+		}
 		return true
 	}
 
@@ -1019,68 +2263,18 @@ func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchRe
 
 	// Check existing maps
 
-	channelsMutex.RLock()
-	if ch, ok := channels[channelName]; ok {
-		for _, n := range sortedKeys(ch.Emotes) {
-			if !add(n, ch.Emotes[n].FilePath, "7tv") {
-				break
-			}
-		}
-	}
-	channelsMutex.RUnlock()
-
-	if len(results) < limit {
-		channelsBTTVMutex.RLock()
-		if m, ok := channelsBTTV[channelName]; ok {
-			for _, n := range sortedKeys(m) {
-				if !add(n, m[n].FilePath, "bttv") {
-					break
-				}
-			}
-		}
-		channelsBTTVMutex.RUnlock()
-	}
-
-	if len(results) < limit {
-		channelsFFZMutex.RLock()
-		if m, ok := channelsFFZ[channelName]; ok {
-			for _, n := range sortedKeys(m) {
-				if !add(n, m[n].FilePath, "ffz") {
-					break
-				}
-			}
-		}
-		channelsFFZMutex.RUnlock()
-	}
-
-	if len(results) < limit {
-		global7TVMutex.RLock()
-		for _, n := range sortedKeys(global7TVEmotes) {
-			if !add(n, global7TVEmotes[n].FilePath, "7tv-global") {
-				break
-			}
-		}
-		global7TVMutex.RUnlock()
-	}
-
-	if len(results) < limit {
-		globalBTTVMutex.RLock()
-		for _, n := range sortedKeys(globalBTTVEmotes) {
-			if !add(n, globalBTTVEmotes[n].FilePath, "bttv-global") {
-				break
-			}
+	for _, set := range a.emoteLookupOrder() {
+		if len(results) >= limit {
+			break
 		}
-		globalBTTVMutex.RUnlock()
-	}
-
-	if len(results) < limit {
-		globalFFZMutex.RLock()
-		for _, n := range sortedKeys(globalFFZEmotes) {
-			if !add(n, globalFFZEmotes[n].FilePath, "ffz-global") {
+		set.mu.RLock()
+		m := set.get(channelName)
+		for _, n := range sortedKeys(m) {
+			if !add(n, m[n].FilePath, m[n].Provider, m[n].Scope) {
 				break
 			}
 		}
-		globalFFZMutex.RUnlock()
+		set.mu.RUnlock()
 	}
 
 	if len(results) >= limit {
@@ -1092,18 +2286,18 @@ func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchRe
 	// from a previous run but haven't been loaded into memory yet.
 
 	type dirSource struct {
-		dir    string
-		source string
+		dir      string
+		provider string
+		scope    string
 	}
-	dirs := []dirSource{
-		{filepath.Join("channels", channelName, "emotes_7tv"), "7tv"},
-		{filepath.Join("channels", channelName, "emotes_bttv"), "bttv"},
-		{filepath.Join("channels", channelName, "emotes_ffz"), "ffz"},
-		{filepath.Join("channels", channelName, "emotes"), "twitch"},
-		{filepath.Join("channels", "global", "emotes_7tv"), "7tv-global"},
-		{filepath.Join("channels", "global", "emotes_bttv"), "bttv-global"},
-		{filepath.Join("channels", "global", "emotes_ffz"), "ffz-global"},
+	var dirs []dirSource
+	for _, provider := range a.config.EmotePriority {
+		dirs = append(dirs,
+			dirSource{a.dataPath("channels", channelName, "emotes_"+provider), provider, "channel"},
+			dirSource{a.dataPath("channels", "global", "emotes_"+provider), provider, "global"},
+		)
 	}
+	dirs = append(dirs, dirSource{a.dataPath("channels", channelName, "emotes"), "twitch", "channel"})
 
 	for _, ds := range dirs {
 		if len(results) >= limit {
@@ -1153,23 +2347,15 @@ func (a *App) SearchEmotes(channelName, query string, limit int) []EmoteSearchRe
 		sort.Slice(cands, func(i, j int) bool { return cands[i].name < cands[j].name })
 
 		for _, c := range cands {
-			if !add(c.name, c.path, ds.source) {
+			if !add(c.name, c.path, ds.provider, ds.scope) {
 				break
 			}
 		}
 	}
 
-	// prefix matches first, then contains
-	sort.SliceStable(results, func(i, j int) bool {
-		iPrefix := strings.HasPrefix(strings.ToLower(results[i].Name), query)
-		jPrefix := strings.HasPrefix(strings.ToLower(results[j].Name), query)
-		if iPrefix != jPrefix {
-			return iPrefix
-		}
-		return results[i].Name < results[j].Name
-	})
-
-	return results
+	// prefix matches first, then contains, each in the order searched
+	results = append(results, contained...)
+	return results[:min(len(results), limit)]
 }
 
 func (a *App) GetEmoteBase64ByPath(filePath string) (string, error) {
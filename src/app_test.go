@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newTestApp is an App on the default config, writing under a temp dir
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	config := defaultTwitchConfig()
+	config.DataDir = t.TempDir()
+	return NewApp("", config, nil, nil)
+}
+
+func TestEncodeMessageEmotesMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "Present_1.png")
+	if err := os.WriteFile(present, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{Channel: "#chan", Content: "Present Gone Present"}
+	emotes := []EmoteInfo{
+		{ID: "1", Name: "Present", FilePath: present, Provider: "bttv", Scope: "channel"},
+		{ID: "2", Name: "Gone", FilePath: filepath.Join(dir, "Gone_2.png"), Provider: "7tv", Scope: "global"},
+		{ID: "2", Name: "Gone", FilePath: filepath.Join(dir, "Gone_2.png"), Provider: "7tv", Scope: "global"},
+	}
+	rendered := (&App{}).encodeMessageEmotes(emotes, msg)
+
+	if _, ok := rendered.images["Present"]; !ok {
+		t.Error("Present has no image")
+	}
+	if _, ok := rendered.images["Gone"]; ok {
+		t.Error("Gone has an image despite the missing file")
+	}
+	// listed once so the frontend keeps the word as text
+	if !slices.Equal(rendered.missing, []string{"Gone"}) {
+		t.Errorf("missing = %v, want [Gone]", rendered.missing)
+	}
+	if msg.Content != "Present Gone Present" {
+		t.Errorf("content changed to %q", msg.Content)
+	}
+}
+
+func TestSearchEmotesPrefixFirst(t *testing.T) {
+	a := newTestApp(t) // nothing on disk
+
+	channelsMutex.Lock()
+	channels["searchtest"] = Channel{Name: "searchtest", Emotes: map[string]EmoteInfo{
+		"catJAM":  {Name: "catJAM", Provider: "7tv", Scope: "channel"},
+		"xJAMmer": {Name: "xJAMmer", Provider: "7tv", Scope: "channel"},
+	}}
+	channelsMutex.Unlock()
+	globalFFZMutex.Lock()
+	globalFFZEmotes["jamTime"] = EmoteInfo{Name: "jamTime", Provider: "ffz", Scope: "global"}
+	globalFFZMutex.Unlock()
+	t.Cleanup(func() {
+		channelsMutex.Lock()
+		delete(channels, "searchtest")
+		channelsMutex.Unlock()
+		globalFFZMutex.Lock()
+		delete(globalFFZEmotes, "jamTime")
+		globalFFZMutex.Unlock()
+	})
+
+	// the global prefix match beats channel emotes that only contain "jam"
+	results := a.SearchEmotes("#searchtest", "jam", 2)
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	if want := []string{"jamTime", "catJAM"}; !slices.Equal(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	if results[0].Provider != "ffz" || results[0].Scope != "global" {
+		t.Errorf("jamTime reported as %s/%s, want ffz/global", results[0].Provider, results[0].Scope)
+	}
+}
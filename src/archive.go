@@ -2,11 +2,11 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -16,6 +16,14 @@ type TwitchRecorder struct {
 	outputDir     string
 	streamlinkCmd *exec.Cmd
 	ffplayCmd     *exec.Cmd
+
+	onStart func(pid int) // streamlink started recording
+	onStop  func()        // and exited, after onStart
+
+	// audio-only playback, guarded by mu
+	mu         sync.Mutex
+	playing    bool
+	onAudioEnd func() // called when playback ends on its own
 }
 
 func NewTwitchRecorder(channel, outputDir string) *TwitchRecorder {
@@ -36,7 +44,7 @@ func (tr *TwitchRecorder) recordStream() error {
 	filename := filepath.Join(channelDir, tr.channel+"_"+timestamp+".mp4")
 	streamURL := "https://twitch.tv/" + tr.channel
 
-	log.Printf("Starting recording: %s", filename)
+	logInfof("Starting recording: %s", filename)
 
 	cmd := exec.Command("streamlink",
 		streamURL,
@@ -55,36 +63,41 @@ func (tr *TwitchRecorder) recordStream() error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
-	streamlinkPids = append(streamlinkPids, cmd.Process.Pid)
+	if tr.onStart != nil {
+		tr.onStart(cmd.Process.Pid)
+	}
+	if tr.onStop != nil {
+		defer tr.onStop()
+	}
 	if err := cmd.Wait(); err != nil {
 		return err
 	}
 
-	log.Printf("Recording saved: %s", filename)
+	logInfof("Recording saved: %s", filename)
 	return nil
 }
 
 func (tr *TwitchRecorder) Start() {
-	log.Printf("Starting recording for %s...", tr.channel)
+	logInfof("Starting recording for %s...", tr.channel)
 
 	if err := tr.recordStream(); err != nil {
-		log.Printf("Recording error: %v", err)
+		logErrorf("Recording error: %v", err)
 	}
 
-	log.Printf("Recording finished for %s", tr.channel)
+	logInfof("Recording finished for %s", tr.channel)
 }
 
 func (tr *TwitchRecorder) StartAudioOnly(volume int) error {
 	streamURL := "https://twitch.tv/" + tr.channel
 
-	tr.streamlinkCmd = exec.Command("streamlink",
+	streamlinkCmd := exec.Command("streamlink",
 		streamURL,
 		"audio_only,160p,worst",
 		"-o", "-",
 		"--twitch-disable-ads",
 	)
 
-	tr.ffplayCmd = exec.Command("ffplay",
+	ffplayCmd := exec.Command("ffplay",
 		"-nodisp",
 		"-autoexit",
 		"-volume", fmt.Sprintf("%d", volume),
@@ -92,34 +105,89 @@ func (tr *TwitchRecorder) StartAudioOnly(volume int) error {
 	)
 
 	if runtime.GOOS == "windows" {
-		tr.streamlinkCmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-		tr.ffplayCmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+		streamlinkCmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+		ffplayCmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
 	}
 
-	tr.ffplayCmd.Stdin, _ = tr.streamlinkCmd.StdoutPipe()
+	ffplayCmd.Stdin, _ = streamlinkCmd.StdoutPipe()
 
-	if err := tr.ffplayCmd.Start(); err != nil {
+	if err := ffplayCmd.Start(); err != nil {
 		return err
 	}
 
-	if err := tr.streamlinkCmd.Start(); err != nil {
-		tr.ffplayCmd.Process.Kill()
+	if err := streamlinkCmd.Start(); err != nil {
+		ffplayCmd.Process.Kill()
 		return err
 	}
 
+	tr.mu.Lock()
+	tr.streamlinkCmd, tr.ffplayCmd = streamlinkCmd, ffplayCmd
+	tr.playing = true
+	tr.mu.Unlock()
+
 	go func() {
-		tr.streamlinkCmd.Wait()
-		tr.ffplayCmd.Wait()
+		streamlinkCmd.Wait()
+		ffplayCmd.Wait()
+
+		// a restart may have replaced us already
+		tr.mu.Lock()
+		current := tr.streamlinkCmd == streamlinkCmd
+		if current {
+			tr.playing = false
+		}
+		onEnd := tr.onAudioEnd
+		tr.mu.Unlock()
+		if current && onEnd != nil {
+			onEnd()
+		}
 	}()
 
 	return nil
 }
 
 func (tr *TwitchRecorder) StopAudio() {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
 	if tr.streamlinkCmd != nil && tr.streamlinkCmd.Process != nil {
 		tr.streamlinkCmd.Process.Kill()
 	}
 	if tr.ffplayCmd != nil && tr.ffplayCmd.Process != nil {
 		tr.ffplayCmd.Process.Kill()
 	}
+	tr.playing = false
+}
+
+// Playing reports whether audio-only playback is running
+func (tr *TwitchRecorder) Playing() bool {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.playing
+}
+
+// newRecorder returns a recorder for channel under the archive dir whose
+// streamlink process is killed when the app exits
+func (a *App) newRecorder(channel string) *TwitchRecorder {
+	tr := NewTwitchRecorder(channel, a.archiveDir)
+	tr.onStart = func(pid int) {
+		a.activeRecordings.Add(1)
+		a.pidsMu.Lock()
+		a.streamlinkPids = append(a.streamlinkPids, pid)
+		a.pidsMu.Unlock()
+	}
+	tr.onStop = func() { a.activeRecordings.Add(-1) }
+	return tr
+}
+
+func (a *App) cleanupStreamlinkProcs() {
+	a.pidsMu.Lock()
+	defer a.pidsMu.Unlock()
+	for _, pid := range a.streamlinkPids {
+		p, err := os.FindProcess(pid)
+		if err == nil {
+			_ = p.Kill()
+			logDebugf("Killed streamlink process: %d", pid)
+		}
+	}
+	// don't kill whatever reuses the pids if called again
+	a.streamlinkPids = nil
 }
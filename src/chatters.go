@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chatters is who's in a channel's chat, by role. Twitch only lists a sample
+// for big channels, Count is the real total either way.
+type Chatters struct {
+	Count        int      `json:"count"`
+	Broadcasters []string `json:"broadcasters"`
+	Moderators   []string `json:"moderators"`
+	VIPs         []string `json:"vips"`
+	Staff        []string `json:"staff"`
+	Viewers      []string `json:"viewers"`
+	Truncated    bool     `json:"truncated"` // lists hold fewer than Count
+}
+
+// The list changes slowly and the query is heavier than the live check
+const chattersTTL = 60 * time.Second
+
+type cachedChatters struct {
+	chatters Chatters
+	fetched  time.Time
+}
+
+// Chatter lists by login, fetched at most once per chattersTTL
+type chattersCache struct {
+	sync.Mutex
+	entries map[string]cachedChatters
+}
+
+// GetChatters returns the users in channel's chat, cached for a minute.
+// Doesn't need membership ($membership) or a login.
+func (a *App) GetChatters(channel string) (Chatters, error) {
+	login, err := normalizeChannelName(channel)
+	if err != nil {
+		return Chatters{}, err
+	}
+
+	a.chatters.Lock()
+	cached, ok := a.chatters.entries[login]
+	a.chatters.Unlock()
+	if ok && time.Since(cached.fetched) < chattersTTL {
+		return cached.chatters, nil
+	}
+
+	query := fmt.Sprintf(`{"query":"query { channel(name:\"%s\") { chatters { count broadcasters { login } moderators { login } vips { login } staff { login } viewers { login } } } }"}`, login)
+
+	type chatter struct {
+		Login string `json:"login"`
+	}
+	var result struct {
+		Data struct {
+			Channel *struct {
+				Chatters *struct {
+					Count        int       `json:"count"`
+					Broadcasters []chatter `json:"broadcasters"`
+					Moderators   []chatter `json:"moderators"`
+					VIPs         []chatter `json:"vips"`
+					Staff        []chatter `json:"staff"`
+					Viewers      []chatter `json:"viewers"`
+				} `json:"chatters"`
+			} `json:"channel"`
+		} `json:"data"`
+	}
+
+	if err := a.gqlRequest(query, &result); err != nil {
+		if ok {
+			// rate limited or down, a stale list beats none
+			return cached.chatters, nil
+		}
+		return Chatters{}, err
+	}
+	if result.Data.Channel == nil || result.Data.Channel.Chatters == nil {
+		return Chatters{}, fmt.Errorf("%w: %s", ErrNoSuchChannel, login)
+	}
+
+	logins := func(list []chatter) []string {
+		out := make([]string, 0, len(list))
+		for _, c := range list {
+			out = append(out, strings.ToLower(c.Login))
+		}
+		return out
+	}
+	raw := result.Data.Channel.Chatters
+	chatters := Chatters{
+		Count:        raw.Count,
+		Broadcasters: logins(raw.Broadcasters),
+		Moderators:   logins(raw.Moderators),
+		VIPs:         logins(raw.VIPs),
+		Staff:        logins(raw.Staff),
+		Viewers:      logins(raw.Viewers),
+	}
+	listed := len(chatters.Broadcasters) + len(chatters.Moderators) + len(chatters.VIPs) +
+		len(chatters.Staff) + len(chatters.Viewers)
+	chatters.Truncated = listed < chatters.Count
+
+	a.chatters.Lock()
+	a.chatters.entries[login] = cachedChatters{chatters: chatters, fetched: time.Now()}
+	a.chatters.Unlock()
+
+	return chatters, nil
+}
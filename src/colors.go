@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseHexColor parses #rrggbb (the # is optional)
+func parseHexColor(hexColor string) (r, g, b int, ok bool) {
+	color := strings.TrimPrefix(hexColor, "#")
+	if len(color) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(color, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// perceivedBrightness is the quick 0-255 brightness used to tell dark from light
+func perceivedBrightness(r, g, b int) float64 {
+	return 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+}
+
+// relativeLuminance as defined by WCAG 2
+func relativeLuminance(r, g, b int) float64 {
+	channel := func(c int) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(r) + 0.7152*channel(g) + 0.0722*channel(b)
+}
+
+// contrastRatio between two colors, 1 (none) to 21 (black on white)
+func contrastRatio(r1, g1, b1, r2, g2, b2 int) float64 {
+	l1 := relativeLuminance(r1, g1, b1)
+	l2 := relativeLuminance(r2, g2, b2)
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// blendTowards moves each channel amount (0-1) of the way to target
+func blendTowards(r, g, b, target int, amount float64) (int, int, int) {
+	mix := func(c int) int {
+		return c + int(float64(target-c)*amount)
+	}
+	return mix(r), mix(g), mix(b)
+}
+
+// adjustNameColor keeps username colors readable on background ($background).
+// Dark names are lightened on dark backgrounds and light names darkened on
+// light ones. Without a minContrast ($mincontrast) that's a single 40% blend,
+// otherwise the color is blended further until it reaches the ratio.
+func adjustNameColor(hexColor, background string, minContrast float64) string {
+	r, g, b, ok := parseHexColor(hexColor)
+	if !ok {
+		return hexColor
+	}
+	br, bg, bb, ok := parseHexColor(background)
+	if !ok {
+		br, bg, bb = 0x1a, 0x1a, 0x1a
+	}
+
+	darkBackground := perceivedBrightness(br, bg, bb) < 128
+	target := 0
+	if darkBackground {
+		target = 255
+	}
+
+	if minContrast <= 0 {
+		if darkBackground == (perceivedBrightness(r, g, b) < 128) {
+			r, g, b = blendTowards(r, g, b, target, 0.4)
+		}
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+
+	for i := 0; i < 10 && contrastRatio(r, g, b, br, bg, bb) < minContrast; i++ {
+		r, g, b = blendTowards(r, g, b, target, 0.2)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
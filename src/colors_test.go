@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestAdjustNameColor(t *testing.T) {
+	tests := []struct {
+		name       string
+		background string
+		color      string
+		want       string
+	}{
+		// dark background, the original behavior
+		{"dark name on dark", "#1a1a1a", "#0000FF", "#6666ff"},
+		{"light name on dark", "#1a1a1a", "#FFD700", "#ffd700"},
+		{"default background", "", "#000000", "#666666"},
+		// light background
+		{"light name on light", "#ffffff", "#FFD700", "#998100"},
+		{"dark name on light", "#ffffff", "#0000FF", "#0000ff"},
+		{"invalid color", "#ffffff", "red", "red"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adjustNameColor(tt.color, tt.background, 0); got != tt.want {
+				t.Errorf("adjustNameColor(%q) on %q = %q, want %q", tt.color, tt.background, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdjustNameColorMinContrast(t *testing.T) {
+	colors := []string{"#0000FF", "#008000", "#FF0000", "#FFD700", "#00FF7F", "#8A2BE2", "#000000", "#FFFFFF"}
+	for _, background := range []string{"#1a1a1a", "#ffffff"} {
+		br, bg, bb, _ := parseHexColor(background)
+		for _, color := range colors {
+			adjusted := adjustNameColor(color, background, 4.5)
+			r, g, b, ok := parseHexColor(adjusted)
+			if !ok {
+				t.Fatalf("adjustNameColor(%q) = %q, not a color", color, adjusted)
+			}
+			if ratio := contrastRatio(r, g, b, br, bg, bb); ratio < 4.5 {
+				t.Errorf("%s on %s adjusted to %s has contrast %.2f, want >= 4.5", color, background, adjusted, ratio)
+			}
+		}
+	}
+}
+
+func TestPaletteColorAdjusted(t *testing.T) {
+	// no color tag, so the name gets a palette color
+	line := "@display-name=Viewer;id=1 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :hi"
+	for _, background := range []string{"#1a1a1a", "#ffffff"} {
+		config := defaultTwitchConfig()
+		config.Background, config.MinContrast = background, 4.5
+		msg := NewClient("#chan", 10, &config).parsePrivMsg(line)
+		r, g, b, _ := parseHexColor(msg.UserColor)
+		br, bg, bb, _ := parseHexColor(background)
+		if ratio := contrastRatio(r, g, b, br, bg, bb); ratio < 4.5 {
+			t.Errorf("palette color %s on %s has contrast %.2f, want >= 4.5", msg.UserColor, background, ratio)
+		}
+	}
+}
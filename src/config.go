@@ -2,69 +2,330 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// Read config file and parse channel=true/false format
-func GetChannelsFromConfig(filePath string) map[string]bool {
-	channels := make(map[string]bool)
+// Twitch logins are 1-25 letters, digits or underscores
+var channelLoginPattern = regexp.MustCompile(`^[a-z0-9_]{1,25}$`)
+
+// normalizeChannelName lowercases name and strips a leading #, returning an
+// error when the result can't be a Twitch login.
+func normalizeChannelName(name string) (string, error) {
+	login := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if !channelLoginPattern.MatchString(login) {
+		return "", fmt.Errorf("invalid channel name %q", name)
+	}
+	return login, nil
+}
+
+// resolveConfigPath picks the config file, in order: the -config flag, the
+// WATCHERINO_CONFIG env var, config.txt next to the executable, then
+// config.txt in the working directory. Shortcuts often start the packaged
+// app from a different working directory, hence the executable check.
+func resolveConfigPath() string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "config" || !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	if path := os.Getenv("WATCHERINO_CONFIG"); path != "" {
+		return path
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(exe), "config.txt")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return "config.txt"
+}
+
+// hasFlag reports whether -name or --name was passed
+func hasFlag(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if strings.HasPrefix(arg, "-") && strings.TrimLeft(arg, "-") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelSettings holds the per-channel flags from config.txt
+type ChannelSettings struct {
+	TTS    bool // play the "is now streaming" TTS when the channel goes live
+	Record bool // archive the stream when it goes live (needs $recording=true)
+	Alert  bool // play the ding for keyword highlights in this channel
+	Switch bool // make this the active channel when it goes live
+}
+
+// Used for channels added at runtime and for flags left out of a config line
+var defaultChannelSettings = ChannelSettings{TTS: true, Record: true, Alert: true}
+
+// parseChannelSettings parses the value side of a channel line. Either the
+// old true/false form or a flag list like tts:on,record:off,alert:on
+func parseChannelSettings(value string) (ChannelSettings, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	switch value {
+	case "true":
+		return ChannelSettings{TTS: true, Record: true, Alert: true}, nil
+	case "false":
+		return ChannelSettings{TTS: false, Record: false, Alert: true}, nil
+	}
+
+	settings := defaultChannelSettings
+	for _, flag := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(flag), ":", 2)
+		if len(kv) != 2 {
+			return settings, fmt.Errorf("invalid flag %q", flag)
+		}
+
+		var on bool
+		switch strings.TrimSpace(kv[1]) {
+		case "on", "true":
+			on = true
+		case "off", "false":
+			on = false
+		default:
+			return settings, fmt.Errorf("invalid value for %s: %q", kv[0], kv[1])
+		}
+
+		switch strings.TrimSpace(kv[0]) {
+		case "tts":
+			settings.TTS = on
+		case "record":
+			settings.Record = on
+		case "alert":
+			settings.Alert = on
+		case "switch":
+			settings.Switch = on
+		default:
+			return settings, fmt.Errorf("unknown flag %q", kv[0])
+		}
+	}
+	return settings, nil
+}
+
+// configLineError points at the config.txt line a problem was found on
+func configLineError(filePath string, lineNum int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", filePath, lineNum, fmt.Sprintf(format, args...))
+}
+
+// LoadChannelsFromConfig parses the channel=true/false or channel=tts:on,...
+// lines. Malformed lines are skipped and reported in the returned error.
+func LoadChannelsFromConfig(filePath string) (map[string]ChannelSettings, error) {
+	channels := make(map[string]ChannelSettings)
 	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return channels, fmt.Errorf("opening config: %w", err)
 	}
 	defer file.Close()
 
+	var errs []error
+	lineNum := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "$") {
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "$") ||
+			strings.HasPrefix(line, aliasPrefix) || strings.HasPrefix(line, ttsMessagePrefix) ||
+			strings.HasPrefix(line, accountPrefix) {
 			continue
 		}
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			fmt.Printf("Skipping invalid line: %s\n", line)
+			errs = append(errs, configLineError(filePath, lineNum, "expected channel=true/false, got %q", line))
 			continue
 		}
 
-		channel := strings.TrimSpace(parts[0])
-		ttsEnabled := strings.TrimSpace(strings.ToLower(parts[1])) == "true"
+		channel, err := normalizeChannelName(parts[0])
+		if err != nil {
+			errs = append(errs, configLineError(filePath, lineNum, "%v", err))
+			continue
+		}
 
-		channels[channel] = ttsEnabled
+		settings, err := parseChannelSettings(parts[1])
+		if err != nil {
+			errs = append(errs, configLineError(filePath, lineNum, "%s: %v", channel, err))
+			continue
+		}
+
+		channels[channel] = settings
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+		errs = append(errs, fmt.Errorf("reading config: %w", err))
+	}
+	return channels, errors.Join(errs...)
+}
+
+// GetChannelsFromConfig is LoadChannelsFromConfig for callers that only need
+// whatever could be parsed, problems are logged
+func GetChannelsFromConfig(filePath string) map[string]ChannelSettings {
+	channels, err := LoadChannelsFromConfig(filePath)
+	if err != nil {
+		logWarnf("Config problems: %v", err)
 	}
 	return channels
 }
 
-// Read Twitch config from file and return TwitchConfig struct
-// Errors out if values arent filled
-func GetTwitchConfigFromFile(filePath string) TwitchConfig {
-	config := TwitchConfig{}
+// channelSettings returns the configured flags for a channel
+func (a *App) channelSettings(channel string) ChannelSettings {
+	if settings, ok := a.channelConfig[strings.TrimPrefix(channel, "#")]; ok {
+		return settings
+	}
+	return defaultChannelSettings
+}
+
+const aliasPrefix = "alias:"
+const ttsMessagePrefix = "ttsmsg:"
+const accountPrefix = "account:"
+
+// Limits for $buffer and SetBufferSize
+const (
+	minBufferSize = 16
+	maxBufferSize = 10000
+)
+
+// Lower live status poll intervals get us rate limited by the GQL API
+const minPollInterval = 30
+
+// defaultTwitchConfig is what LoadTwitchConfig starts from, so settings a
+// config.txt leaves out (or gets wrong) keep working values
+func defaultTwitchConfig() TwitchConfig {
+	return TwitchConfig{
+		SendRateLimit:      20,
+		SendRateWindow:     30 * time.Second,
+		JoinRateLimit:      20,
+		JoinRateWindow:     10 * time.Second,
+		CollapseWindow:     10 * time.Second,
+		EmoteCacheSize:     2000,
+		AudioFollowsActive: true,
+		PollInterval:       2 * time.Minute,
+		PollStagger:        500 * time.Millisecond,
+		ConnectConcurrency: 5,
+		ConnectStagger:     200 * time.Millisecond,
+		TimeFormat:         "15:04:05",
+		Background:         "#1A1A1A",
+		IRCServer:          "irc.chat.twitch.tv",
+		BufferSize:         256,
+		EmotePriority:      defaultEmotePriority,
+	}
+}
+
+// LoadTwitchConfig reads the $key=value settings from the config file.
+// It always returns a usable config, falling back to defaults for anything
+// missing or invalid, and reports every problem found (with line numbers)
+// in the error so the UI can show them instead of the app exiting.
+func LoadTwitchConfig(filePath string) (TwitchConfig, error) {
+	config := defaultTwitchConfig()
 	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return config, fmt.Errorf("opening config: %w", err)
 	}
 	defer file.Close()
 
+	var errs []error
+	lineNum := 0
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
+		lineNum++
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 
+		if strings.HasPrefix(line, aliasPrefix) {
+			// alias:login=Display Name, only ever shown in the UI
+			login, alias, ok := strings.Cut(strings.TrimPrefix(line, aliasPrefix), "=")
+			if !ok || strings.TrimSpace(alias) == "" {
+				errs = append(errs, configLineError(filePath, lineNum, "expected alias:login=name, got %q", line))
+				continue
+			}
+			login, err := normalizeChannelName(login)
+			if err != nil {
+				errs = append(errs, configLineError(filePath, lineNum, "%v", err))
+				continue
+			}
+			if config.Aliases == nil {
+				config.Aliases = make(map[string]string)
+			}
+			config.Aliases[login] = strings.TrimSpace(alias)
+			continue
+		}
+
+		if strings.HasPrefix(line, ttsMessagePrefix) {
+			// ttsmsg:login=text, said after the channel name instead of $ttsmessage
+			login, message, ok := strings.Cut(strings.TrimPrefix(line, ttsMessagePrefix), "=")
+			if !ok || strings.TrimSpace(message) == "" {
+				errs = append(errs, configLineError(filePath, lineNum, "expected ttsmsg:login=text, got %q", line))
+				continue
+			}
+			login, err := normalizeChannelName(login)
+			if err != nil {
+				errs = append(errs, configLineError(filePath, lineNum, "%v", err))
+				continue
+			}
+			if config.TTSMessages == nil {
+				config.TTSMessages = make(map[string]string)
+			}
+			config.TTSMessages[login] = strings.TrimSpace(message)
+			continue
+		}
+
+		if strings.HasPrefix(line, accountPrefix) {
+			// account:name=nick,oauth:token, picked with $account or SetActiveAccount
+			name, value, _ := strings.Cut(strings.TrimPrefix(line, accountPrefix), "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			nick, token, ok := strings.Cut(value, ",")
+			nick, token = strings.TrimSpace(nick), strings.TrimSpace(token)
+			if name == "" || !ok || nick == "" || token == "" {
+				errs = append(errs, configLineError(filePath, lineNum, "expected account:name=nick,oauth:token"))
+				continue
+			}
+			if name == defaultAccount || name == anonymousAccount {
+				errs = append(errs, configLineError(filePath, lineNum, "account name %q is reserved", name))
+				continue
+			}
+			if !strings.HasPrefix(token, "oauth:") {
+				token = "oauth:" + token
+			}
+			if config.Accounts == nil {
+				config.Accounts = make(map[string]Account)
+			}
+			config.Accounts[name] = Account{Nick: nick, OauthToken: token}
+			continue
+		}
+
 		if !strings.HasPrefix(line, "$") {
+			// a channel line, LoadChannelsFromConfig reports bad ones
+			if login, err := normalizeChannelName(strings.SplitN(line, "=", 2)[0]); err == nil {
+				config.ChannelOrder = append(config.ChannelOrder, login)
+			}
 			continue
 		}
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
+			errs = append(errs, configLineError(filePath, lineNum, "expected $key=value, got %q", line))
 			continue
 		}
 
@@ -74,38 +335,431 @@ func GetTwitchConfigFromFile(filePath string) TwitchConfig {
 		tmp := make([]string, 0)
 		switch key {
 		case "$nick":
+			if strings.ContainsAny(value, " \t") {
+				errs = append(errs, configLineError(filePath, lineNum, "$nick must not contain spaces"))
+				continue
+			}
 			config.Nickname = value
 		case "$oauth":
+			if value == "" {
+				continue
+			}
 			if !strings.HasPrefix(value, "oauth:") {
 				config.OauthToken = "oauth:" + value
 			} else {
 				config.OauthToken = value
 			}
+		case "$account":
+			// account used at startup, checked once the file is read
+			config.Account = strings.ToLower(value)
 		case "$filter":
 			tmp = append(tmp, strings.Split(value, ",")...)
 			config.FilterList = tmp
+		case "$ignore":
+			// logins, messages from them are dropped before display
+			ignored := make(map[string]bool)
+			for _, login := range strings.Split(value, ",") {
+				if login = strings.ToLower(strings.TrimSpace(login)); login != "" {
+					ignored[login] = true
+				}
+			}
+			config.IgnoredUsers = ignored
+		case "$ignorelogs":
+			config.IgnoreInLogs = strings.ToLower(value) == "true"
 		case "$recording":
 			config.RecordingEnabled = strings.ToLower(value) == "true"
 		case "$archivedir":
 			config.ArchiveDir = value
+		case "$datadir":
+			// logs, emotes, tts, exports and prefs.json go here
+			config.DataDir = value
 		case "$ttspath":
 			config.TTSPath = value
 		case "$ttsmessage":
 			config.TTSMessage = value
+		case "$defaultchannel", "$default_channel":
+			// shown on startup instead of the first channel listed
+			login, err := normalizeChannelName(value)
+			if err != nil {
+				errs = append(errs, configLineError(filePath, lineNum, "%v", err))
+				continue
+			}
+			config.DefaultChannel = login
+		case "$audiofollows":
+			// false = switching chat leaves stream audio alone
+			config.AudioFollowsActive = strings.ToLower(value) == "true"
+		case "$highlightfirst":
+			config.HighlightFirst = strings.ToLower(value) == "true"
+		case "$highlightwebhook", "$highlight_webhook":
+			// POSTed a JSON body for every highlight
+			config.HighlightWebhook = value
+		case "$highlightexec", "$highlight_exec":
+			// run for every highlight, details in WATCHERINO_* env vars; quote paths with spaces
+			config.HighlightExec = value
+		case "$autoswitch", "$autoswitch_on_live":
+			// switch to any channel that goes live, same as switch:on everywhere
+			config.AutoSwitch = strings.ToLower(value) == "true"
+		case "$loglevel":
+			// debug logs every step, the default info only what matters
+			level, err := parseLogLevel(value)
+			if err != nil {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $loglevel %q, %v", value, err))
+				continue
+			}
+			config.LogLevel = level
+		case "$console":
+			// same as -console, log to a console as well as the log file
+			config.Console = strings.ToLower(value) == "true"
+		case "$reconnectonlive":
+			// reconnect a channel's chat on going live unless it's clearly working
+			config.ReconnectOnLive = strings.ToLower(value) == "true"
+		case "$notifyhighlights":
+			config.NotifyHighlights = strings.ToLower(value) == "true"
+		case "$notifylive":
+			config.NotifyLive = strings.ToLower(value) == "true"
+		case "$timeformat":
+			// 24h, 12h or a Go time layout like 15:04
+			layout, ok := parseTimeFormat(value)
+			if !ok {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $timeformat %q, expected 24h, 12h or a layout like 15:04:05", value))
+				continue
+			}
+			config.TimeFormat = layout
+		case "$logdate":
+			config.LogDate = strings.ToLower(value) == "true"
+		case "$background":
+			// chat background, username colors are adjusted against it
+			if _, _, _, ok := parseHexColor(value); !ok {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $background %q, expected #rrggbb", value))
+				continue
+			}
+			config.Background = value
+		case "$palette":
+			// default username colors, e.g. #FF0000,#0000FF,#008000
+			var palette []string
+			for _, color := range strings.Split(value, ",") {
+				color = strings.TrimSpace(color)
+				if color == "" {
+					continue
+				}
+				if _, _, _, ok := parseHexColor(color); !ok || !strings.HasPrefix(color, "#") {
+					errs = append(errs, configLineError(filePath, lineNum, "invalid $palette color %q, expected #rrggbb", color))
+					continue
+				}
+				palette = append(palette, color)
+			}
+			config.Palette = palette
+		case "$mincontrast":
+			// WCAG contrast ratio, e.g. 4.5
+			ratio, err := strconv.ParseFloat(value, 64)
+			if err != nil || ratio < 1 || ratio > 21 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $mincontrast %q, expected a ratio between 1 and 21", value))
+				continue
+			}
+			config.MinContrast = ratio
+		case "$firehose":
+			// emit multi-message for every message in every channel
+			config.Firehose = strings.ToLower(value) == "true"
+		case "$rawirc":
+			// log every IRC line to logs/<date>_rawirc.txt, for debugging
+			config.RawIRC = strings.ToLower(value) == "true"
+		case "$modlog":
+			// log bans/timeouts/deletes per channel, needs $oauth
+			config.ModLog = strings.ToLower(value) == "true"
+		case "$membership":
+			// user-joined/user-left events, only reliable in small channels
+			config.Membership = strings.ToLower(value) == "true"
+		case "$collapse":
+			config.CollapseRepeats = strings.ToLower(value) == "true"
+		case "$highlightcooldown":
+			// seconds between highlight dings, matches in between are only shown
+			secs, err := strconv.Atoi(value)
+			if err != nil || secs < 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $highlightcooldown %q, expected seconds", value))
+				continue
+			}
+			config.HighlightCooldown = time.Duration(secs) * time.Second
+		case "$collapsewindow":
+			// seconds
+			secs, err := strconv.Atoi(value)
+			if err != nil || secs <= 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $collapsewindow %q, expected seconds", value))
+				continue
+			}
+			config.CollapseWindow = time.Duration(secs) * time.Second
+		case "$quiethours":
+			// e.g. 01:00-09:00
+			qh, ok := parseQuietHours(value)
+			if !ok {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $quiethours %q, expected HH:MM-HH:MM", value))
+				continue
+			}
+			config.QuietHours = qh
+		case "$7tvexclude":
+			// e.g. nsfw,epilepsy,zerowidth
+			config.SevenTVExclude = 0
+			for _, name := range strings.Split(value, ",") {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "" {
+					continue
+				}
+				flag, ok := sevenTVFlags[name]
+				if !ok {
+					errs = append(errs, configLineError(filePath, lineNum, "unknown 7TV flag %q", name))
+					continue
+				}
+				config.SevenTVExclude |= flag
+			}
+		case "$7tvmax":
+			max, err := strconv.Atoi(value)
+			if err != nil || max < 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $7tvmax %q, expected a number", value))
+				continue
+			}
+			config.SevenTVMax = max
+		case "$7tvscale":
+			// source size downloaded before the resize, 1x..4x
+			scale, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(value), "x"))
+			if err != nil || scale < 1 || scale > 4 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $7tvscale %q, expected 1x, 2x, 3x or 4x", value))
+				continue
+			}
+			config.SevenTVScale = scale
+		case "$emotecache":
+			// max emotes kept in memory, 0 for no limit
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $emotecache %q, expected a number", value))
+				continue
+			}
+			config.EmoteCacheSize = size
+		case "$pollinterval":
+			// seconds between live status checks
+			secs, err := strconv.Atoi(value)
+			if err != nil || secs < minPollInterval {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $pollinterval %q, expected at least %d seconds", value, minPollInterval))
+				continue
+			}
+			config.PollInterval = time.Duration(secs) * time.Second
+		case "$pollstagger":
+			// milliseconds between channels within one check
+			ms, err := strconv.Atoi(value)
+			if err != nil || ms < 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $pollstagger %q, expected milliseconds", value))
+				continue
+			}
+			config.PollStagger = time.Duration(ms) * time.Millisecond
+		case "$emotepriority", "$emote_priority":
+			// e.g. bttv,7tv,ffz, providers left out go last in the default order
+			priority, err := parseEmotePriority(value)
+			if err != nil {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $emotepriority: %v", err))
+				continue
+			}
+			config.EmotePriority = priority
+		case "$emoteblacklist", "$emote_blacklist":
+			// exact, case-sensitive emote names that stay plain text
+			blacklist := make(map[string]bool)
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					blacklist[name] = true
+				}
+			}
+			config.EmoteBlacklist = blacklist
+		case "$emotes":
+			// off = text only, emote names stay as plain text
+			switch strings.ToLower(value) {
+			case "on", "true":
+				config.EmotesOff = false
+			case "off", "false":
+				config.EmotesOff = true
+			default:
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $emotes %q, expected on or off", value))
+				continue
+			}
+		case "$truncate":
+			// characters shown before a long message is collapsed, 0 = off
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $truncate %q, expected a number of characters", value))
+				continue
+			}
+			config.TruncateLength = n
+		case "$hidelinks":
+			// mask = show [link], strip = remove, off = show as-is
+			mode := strings.ToLower(value)
+			switch mode {
+			case "off", "false":
+				config.HideLinks = ""
+			case "mask", "strip":
+				config.HideLinks = mode
+			default:
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $hidelinks %q, expected mask, strip or off", value))
+				continue
+			}
+		case "$buffer":
+			// messages kept per channel
+			size, err := strconv.Atoi(value)
+			if err != nil || size < minBufferSize || size > maxBufferSize {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $buffer %q, expected %d to %d messages", value, minBufferSize, maxBufferSize))
+				continue
+			}
+			config.BufferSize = size
+		case "$ircserver":
+			if value == "" || strings.ContainsAny(value, " \t:") {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $ircserver %q, expected a host name", value))
+				continue
+			}
+			config.IRCServer = value
+		case "$ircport":
+			// defaults to 6667, or 6697 with $irctls
+			port, err := strconv.Atoi(value)
+			if err != nil || port <= 0 || port > 65535 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $ircport %q, expected a port number", value))
+				continue
+			}
+			config.IRCPort = port
+		case "$irctls", "$irc_tls":
+			config.IRCTLS = strings.ToLower(value) == "true"
+		case "$multiplex":
+			// one IRC connection for every channel instead of one each
+			config.Multiplex = strings.ToLower(value) == "true"
+		case "$connectconcurrency":
+			// connections opened at once on startup
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $connectconcurrency %q, expected a number above 0", value))
+				continue
+			}
+			config.ConnectConcurrency = n
+		case "$connectstagger":
+			// milliseconds between starting connections on startup
+			ms, err := strconv.Atoi(value)
+			if err != nil || ms < 0 {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $connectstagger %q, expected milliseconds", value))
+				continue
+			}
+			config.ConnectStagger = time.Duration(ms) * time.Millisecond
+		case "$joinrate":
+			// joins/seconds across all channels, e.g. 20/10 (2000/10 for verified bots)
+			limit, window, ok := parseRate(value)
+			if !ok {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $joinrate %q, expected joins/seconds", value))
+				continue
+			}
+			config.JoinRateLimit = limit
+			config.JoinRateWindow = window
+		case "$sendrate":
+			// messages/seconds, e.g. 20/30 or 100/30 for mods
+			limit, window, ok := parseRate(value)
+			if !ok {
+				errs = append(errs, configLineError(filePath, lineNum, "invalid $sendrate %q, expected messages/seconds", value))
+				continue
+			}
+			config.SendRateLimit = limit
+			config.SendRateWindow = window
+		default:
+			errs = append(errs, configLineError(filePath, lineNum, "unknown setting %s", key))
 		}
 
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+		errs = append(errs, fmt.Errorf("reading config: %w", err))
 	}
 
-	if config.Nickname == "" {
-		log.Fatal("Missing $nick in config file")
+	// both are optional, without them chat is read anonymously
+	if config.OauthToken != "" && config.Nickname == "" {
+		errs = append(errs, fmt.Errorf("%s: $oauth is set but $nick is missing, reading chat anonymously", filePath))
 	}
-	if config.OauthToken == "" {
-		log.Fatal("Missing $oauth in config file")
+	if _, ok := config.account(config.Account); !ok {
+		errs = append(errs, fmt.Errorf("%s: $account %q isn't defined, using $nick/$oauth", filePath, config.Account))
+		config.Account = ""
 	}
 
+	return config, errors.Join(errs...)
+}
+
+// GetTwitchConfigFromFile is LoadTwitchConfig for callers that only need
+// whatever could be parsed, problems are logged
+func GetTwitchConfigFromFile(filePath string) TwitchConfig {
+	config, err := LoadTwitchConfig(filePath)
+	if err != nil {
+		logWarnf("Config problems: %v", err)
+	}
 	return config
 }
+
+// parseRate parses an "N/seconds" rate like "20/30"
+func parseRate(value string) (int, time.Duration, bool) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	n, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	secs, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil || n <= 0 || secs <= 0 {
+		return 0, 0, false
+	}
+	return n, time.Duration(secs) * time.Second, true
+}
+
+// parseEmotePriority parses a comma separated provider list. Providers not
+// mentioned keep their default relative order after the listed ones.
+func parseEmotePriority(value string) ([]string, error) {
+	var priority []string
+	listed := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := emoteProviders[name]; !ok {
+			return nil, fmt.Errorf("unknown provider %q, expected 7tv, bttv or ffz", name)
+		}
+		if listed[name] {
+			continue
+		}
+		listed[name] = true
+		priority = append(priority, name)
+	}
+	for _, name := range defaultEmotePriority {
+		if !listed[name] {
+			priority = append(priority, name)
+		}
+	}
+	return priority, nil
+}
+
+// parseTimeFormat maps the $timeformat shorthands to Go layouts. Anything
+// else has to be a layout, i.e. change when formatted.
+func parseTimeFormat(value string) (string, bool) {
+	switch strings.ToLower(value) {
+	case "24h":
+		return "15:04:05", true
+	case "12h":
+		return "3:04:05 PM", true
+	}
+	if value == "" || time.Date(2001, 2, 3, 4, 5, 6, 0, time.Local).Format(value) == value {
+		return "", false
+	}
+	return value, true
+}
+
+// parseQuietHours parses an "HH:MM-HH:MM" window
+func parseQuietHours(value string) (QuietHours, bool) {
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 {
+		return QuietHours{}, false
+	}
+	start, err1 := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	end, err2 := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		return QuietHours{}, false
+	}
+	return QuietHours{
+		Start: start.Hour()*60 + start.Minute(),
+		End:   end.Hour()*60 + end.Minute(),
+		Set:   true,
+	}, true
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// loadTestConfig writes lines to a config file and loads it
+func loadTestConfig(t *testing.T, lines string) TwitchConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.txt")
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadTwitchConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected config errors: %v", err)
+	}
+	return config
+}
+
+func TestConfigEmotePriority(t *testing.T) {
+	for _, key := range []string{"$emotepriority", "$emote_priority"} {
+		config := loadTestConfig(t, key+"=bttv,7tv\n")
+		if want := []string{"bttv", "7tv", "ffz"}; !slices.Equal(config.EmotePriority, want) {
+			t.Errorf("%s: got %v, want %v", key, config.EmotePriority, want)
+		}
+	}
+}
+
+func TestConfigAutoSwitch(t *testing.T) {
+	for _, key := range []string{"$autoswitch", "$autoswitch_on_live"} {
+		if config := loadTestConfig(t, key+"=true\n"); !config.AutoSwitch {
+			t.Errorf("%s=true didn't turn on auto-switch", key)
+		}
+	}
+}
+
+func TestConfigIRCTLS(t *testing.T) {
+	for _, key := range []string{"$irctls", "$irc_tls"} {
+		if config := loadTestConfig(t, key+"=true\n"); !config.IRCTLS {
+			t.Errorf("%s=true didn't turn on TLS", key)
+		}
+	}
+}
+
+func TestConfigEmoteBlacklist(t *testing.T) {
+	for _, key := range []string{"$emoteblacklist", "$emote_blacklist"} {
+		config := loadTestConfig(t, key+"=LUL, Kappa\n")
+		if !config.EmoteBlacklist["LUL"] || !config.EmoteBlacklist["Kappa"] || len(config.EmoteBlacklist) != 2 {
+			t.Errorf("%s: got %v, want LUL and Kappa", key, config.EmoteBlacklist)
+		}
+	}
+}
+
+func TestConfigHighlightHooks(t *testing.T) {
+	for _, prefix := range []string{"$highlight", "$highlight_"} {
+		config := loadTestConfig(t, prefix+"webhook=https://example.com/hook\n"+prefix+"exec=notify.exe --quiet\n")
+		if config.HighlightWebhook != "https://example.com/hook" {
+			t.Errorf("%swebhook: got %q", prefix, config.HighlightWebhook)
+		}
+		if config.HighlightExec != "notify.exe --quiet" {
+			t.Errorf("%sexec: got %q", prefix, config.HighlightExec)
+		}
+	}
+}
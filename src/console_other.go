@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+func isConsoleAvailable() bool {
+	return true
+}
+
+// openConsole returns somewhere to write logs for -console
+func openConsole() (io.Writer, error) {
+	return os.Stdout, nil
+}
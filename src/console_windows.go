@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleWindow = kernel32.NewProc("GetConsoleWindow")
+	procAttachConsole    = kernel32.NewProc("AttachConsole")
+	procAllocConsole     = kernel32.NewProc("AllocConsole")
+)
+
+// ATTACH_PARENT_PROCESS, (DWORD)-1
+const attachParentProcess = ^uint32(0)
+
+// isConsoleAvailable reports whether we already have a console, i.e. the
+// exe wasn't built as a GUI app (wails dev, go run)
+func isConsoleAvailable() bool {
+	hwnd, _, _ := procGetConsoleWindow.Call()
+	return hwnd != 0
+}
+
+// openConsole returns somewhere to write logs for -console. GUI builds have
+// no console: attach to the one we were started from, or open a new one
+// when started from Explorer.
+func openConsole() (io.Writer, error) {
+	if isConsoleAvailable() {
+		return os.Stdout, nil
+	}
+	if r, _, _ := procAttachConsole.Call(uintptr(attachParentProcess)); r == 0 {
+		if r, _, err := procAllocConsole.Call(); r == 0 {
+			return nil, fmt.Errorf("AllocConsole: %w", err)
+		}
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	os.Stdout = out
+	os.Stderr = out
+	return out, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	goruntime "runtime"
+	"sort"
+	"strings"
+)
+
+// ChannelDiagnostics is the state of a single connection
+type ChannelDiagnostics struct {
+	Channel     string `json:"channel"`
+	IsConnected bool   `json:"isConnected"`
+	RoomID      string `json:"roomId"`
+	Joined      bool   `json:"joined"`
+	Buffered    int    `json:"buffered"`
+	ViewerCount int    `json:"viewerCount"`
+	Live        bool   `json:"live"`
+	LastError   string `json:"lastError,omitempty"`
+	LastErrorAt int64  `json:"lastErrorAt,omitempty"` // unix ms
+}
+
+// Diagnostics is a one-shot health view of the app
+type Diagnostics struct {
+	ActiveChannel    string               `json:"activeChannel"`
+	Channels         []ChannelDiagnostics `json:"channels"`
+	Goroutines       int                  `json:"goroutines"`
+	ActiveRecordings int                  `json:"activeRecordings"`
+	EmoteCacheSizes  map[string]int       `json:"emoteCacheSizes"`
+	AudioAvailable   bool                 `json:"audioAvailable"`
+	TTSAvailable     bool                 `json:"ttsAvailable"`
+	ConfigErrors     []string             `json:"configErrors"`
+}
+
+// GetDiagnostics collects connection, recording and emote cache state.
+// Locks are only held long enough to copy values out.
+func (a *App) GetDiagnostics() Diagnostics {
+	a.connectionsMu.RLock()
+	activeChannel := a.activeChannel
+	conns := make([]*ChannelConnection, 0, len(a.connections))
+	for _, conn := range a.connections {
+		conns = append(conns, conn)
+	}
+	liveStatuses := make(map[string]bool, len(a.liveStatuses))
+	for channel, live := range a.liveStatuses {
+		liveStatuses[channel] = live
+	}
+	// refused channels have no connection left, report them on their own
+	refused := make(map[string]channelError)
+	for channel, joinErr := range a.joinErrors {
+		if _, connected := a.connections[channel]; !connected {
+			refused[channel] = joinErr
+		}
+	}
+	a.connectionsMu.RUnlock()
+
+	channelDiags := make([]ChannelDiagnostics, 0, len(conns))
+	for _, conn := range conns {
+		conn.mu.RLock()
+		diag := ChannelDiagnostics{
+			Channel:     conn.channel,
+			IsConnected: conn.isConnected,
+			RoomID:      conn.roomID,
+			Buffered:    len(conn.messages),
+			ViewerCount: conn.viewerCount,
+		}
+		if conn.lastErr != nil {
+			diag.LastError = conn.lastErr.Error()
+			diag.LastErrorAt = conn.lastErrAt.UnixMilli()
+		}
+		client := conn.client
+		conn.mu.RUnlock()
+
+		if client != nil {
+			diag.Joined = client.IsJoined()
+		}
+		diag.Live = liveStatuses[strings.TrimPrefix(diag.Channel, "#")]
+		channelDiags = append(channelDiags, diag)
+	}
+	for channel, joinErr := range refused {
+		channelDiags = append(channelDiags, ChannelDiagnostics{
+			Channel:     channel,
+			LastError:   joinErr.err.Error(),
+			LastErrorAt: joinErr.at.UnixMilli(),
+			Live:        liveStatuses[strings.TrimPrefix(channel, "#")],
+		})
+	}
+	sort.Slice(channelDiags, func(i, j int) bool {
+		return channelDiags[i].Channel < channelDiags[j].Channel
+	})
+
+	return Diagnostics{
+		ActiveChannel:    activeChannel,
+		Channels:         channelDiags,
+		Goroutines:       goruntime.NumGoroutine(),
+		ActiveRecordings: int(a.activeRecordings.Load()),
+		EmoteCacheSizes:  a.emoteCacheSizes(),
+		AudioAvailable:   a.audioOut != nil,
+		TTSAvailable:     a.ttsAvailable,
+		ConfigErrors:     a.GetConfigErrors(),
+	}
+}
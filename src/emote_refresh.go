@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Channels refreshed at once by RefreshAllEmotes. Each one downloads its new
+// emotes one at a time, so this bounds the requests in flight at the CDNs.
+const emoteRefreshConcurrency = 3
+
+// RefreshAllEmotes refetches the global emotes and the 7TV/BTTV/FFZ sets of
+// every connected channel in the background, picking up emotes added since
+// they were loaded. Emits "emotes-refreshed" when done.
+func (a *App) RefreshAllEmotes() error {
+	if a.config.EmotesOff {
+		return errors.New("emotes are off")
+	}
+	if !a.refreshingEmotes.CompareAndSwap(false, true) {
+		return errors.New("emote refresh already running")
+	}
+
+	// channels that haven't had a message yet have no room id, they load
+	// their emotes on the first one anyway
+	rooms := make(map[string]string)
+	a.connectionsMu.RLock()
+	for name, conn := range a.connections {
+		conn.mu.RLock()
+		if conn.isConnected && conn.roomID != "" && !conn.replay {
+			rooms[name] = conn.roomID
+		}
+		conn.mu.RUnlock()
+	}
+	a.connectionsMu.RUnlock()
+
+	go func() {
+		defer a.refreshingEmotes.Store(false)
+
+		var mu sync.Mutex
+		failed := make([]string, 0)
+		fail := func(what string, err error) {
+			logWarnf("Emote refresh: %s: %v", what, err)
+			mu.Lock()
+			failed = append(failed, what)
+			mu.Unlock()
+		}
+
+		if err := a.fetch7TVGlobalEmotes(); err != nil {
+			fail("7tv global", err)
+		}
+		if err := a.fetchBTTVGlobalEmotes(); err != nil {
+			fail("bttv global", err)
+		}
+		if err := a.fetchFFZGlobalEmotes(); err != nil {
+			fail("ffz global", err)
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, emoteRefreshConcurrency)
+		for channel, roomID := range rooms {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(channel, roomID string) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				name := strings.TrimPrefix(channel, "#")
+				if err := a.fetch7TVEmotes(roomID, channel); err != nil {
+					fail(name+" 7tv", err)
+				}
+				if err := a.fetchBTTVChannelEmotes(roomID, channel); err != nil {
+					fail(name+" bttv", err)
+				}
+				if err := a.fetchFFZChannelEmotes(roomID, channel); err != nil {
+					fail(name+" ffz", err)
+				}
+			}(channel, roomID)
+		}
+		wg.Wait()
+
+		logInfof("Refreshed emotes for %d channels, %d fetches failed", len(rooms), len(failed))
+		a.emit("emotes-refreshed", map[string]interface{}{
+			"channels": len(rooms),
+			"failed":   failed,
+		})
+	}()
+
+	return nil
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"container/list"
 	"encoding/json"
 	"fmt"
 	"image"
 	"image/gif"
 	"image/png"
 	"io"
-	"log"
+	"io/fs"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -17,6 +20,7 @@ import (
 	"sync"
 
 	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
 )
 
 // Global emote storage
@@ -42,6 +46,10 @@ type EmoteInfo struct {
 	URL       string
 	FilePath  string
 	ImageURL  string
+	Provider  string // twitch, 7tv, bttv or ffz
+	Scope     string // channel or global, empty for twitch
+	Width     int    // display size of the stored image, 0 if unknown
+	Height    int
 	Positions []EmotePosition
 }
 
@@ -56,68 +64,86 @@ type Channel struct {
 	Emotes map[string]EmoteInfo
 }
 
-func findEmote(channelName, word string) (EmoteInfo, bool) {
-	channelName = strings.TrimPrefix(channelName, "#")
+// emoteSet is one provider's emotes in one scope. get must be called with
+// mu held.
+type emoteSet struct {
+	mu  *sync.RWMutex
+	get func(channelName string) map[string]EmoteInfo
+}
 
-	// Check channel-specific 7TV emotes
-	channelsMutex.RLock()
-	if channel, ok := channels[channelName]; ok {
-		if e, ok := channel.Emotes[word]; ok {
-			channelsMutex.RUnlock()
-			return e, true
-		}
-	}
-	channelsMutex.RUnlock()
+// Channel scope before global within each provider, the providers
+// themselves are tried in $emotepriority order.
+var emoteProviders = map[string][2]emoteSet{
+	"7tv": {
+		{&channelsMutex, func(ch string) map[string]EmoteInfo { return channels[ch].Emotes }},
+		{&global7TVMutex, func(string) map[string]EmoteInfo { return global7TVEmotes }},
+	},
+	"bttv": {
+		{&channelsBTTVMutex, func(ch string) map[string]EmoteInfo { return channelsBTTV[ch] }},
+		{&globalBTTVMutex, func(string) map[string]EmoteInfo { return globalBTTVEmotes }},
+	},
+	"ffz": {
+		{&channelsFFZMutex, func(ch string) map[string]EmoteInfo { return channelsFFZ[ch] }},
+		{&globalFFZMutex, func(string) map[string]EmoteInfo { return globalFFZEmotes }},
+	},
+}
 
-	// Check global 7TV emotes
-	global7TVMutex.RLock()
-	if e, ok := global7TVEmotes[word]; ok {
-		global7TVMutex.RUnlock()
-		return e, true
-	}
-	global7TVMutex.RUnlock()
+var defaultEmotePriority = []string{"7tv", "bttv", "ffz"}
 
-	// Check channel-specific BTTV emotes
-	channelsBTTVMutex.RLock()
-	if channelEmotes, ok := channelsBTTV[channelName]; ok {
-		if e, ok := channelEmotes[word]; ok {
-			channelsBTTVMutex.RUnlock()
-			return e, true
-		}
+// emoteLookupOrder lists every emote set in precedence order
+func (a *App) emoteLookupOrder() []emoteSet {
+	order := make([]emoteSet, 0, 2*len(a.config.EmotePriority))
+	for _, provider := range a.config.EmotePriority {
+		sets := emoteProviders[provider]
+		order = append(order, sets[0], sets[1])
 	}
-	channelsBTTVMutex.RUnlock()
+	return order
+}
 
-	// Check global BTTV emotes
-	globalBTTVMutex.RLock()
-	if e, ok := globalBTTVEmotes[word]; ok {
-		globalBTTVMutex.RUnlock()
-		return e, true
-	}
-	globalBTTVMutex.RUnlock()
+func (a *App) findEmote(channelName, word string) (EmoteInfo, bool) {
+	channelName = strings.TrimPrefix(channelName, "#")
 
-	// Check channel-specific FFZ emotes
-	channelsFFZMutex.RLock()
-	if channelEmotes, ok := channelsFFZ[channelName]; ok {
-		if e, ok := channelEmotes[word]; ok {
-			channelsFFZMutex.RUnlock()
+	for _, set := range a.emoteLookupOrder() {
+		set.mu.RLock()
+		e, ok := set.get(channelName)[word]
+		set.mu.RUnlock()
+		if ok {
 			return e, true
 		}
 	}
-	channelsFFZMutex.RUnlock()
+	return EmoteInfo{}, false
+}
 
-	// Check global FFZ emotes
-	globalFFZMutex.RLock()
-	if e, ok := globalFFZEmotes[word]; ok {
-		globalFFZMutex.RUnlock()
-		return e, true
+// listEmotes returns every third-party emote usable in a channel, sorted by
+// name. Names defined by more than one source resolve like findEmote does.
+func (a *App) listEmotes(channelName string) []EmoteInfo {
+	channelName = strings.TrimPrefix(channelName, "#")
+	seen := make(map[string]bool)
+	var result []EmoteInfo
+
+	for _, set := range a.emoteLookupOrder() {
+		set.mu.RLock()
+		for name, emote := range set.get(channelName) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			result = append(result, emote)
+		}
+		set.mu.RUnlock()
 	}
-	globalFFZMutex.RUnlock()
 
-	return EmoteInfo{}, false
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
 }
 
-// ParseEmotes extracts emote information from a Twitch message
-func ParseEmotes(msg *Message) []EmoteInfo {
+// parseEmotes extracts emote information from a Twitch message
+func (a *App) parseEmotes(msg *Message) []EmoteInfo {
+	if a.config.EmotesOff {
+		return nil
+	}
 
 	// Parse Twitch emotes first
 	var emotes []EmoteInfo
@@ -150,10 +176,16 @@ func ParseEmotes(msg *Message) []EmoteInfo {
 				}
 
 				emoteName := string(contentRunes[start : end+1])
+				if a.config.EmoteBlacklist[emoteName] {
+					continue
+				}
 				emotes = append(emotes, EmoteInfo{
-					ID:   emoteID,
-					Name: emoteName,
-					URL:  fmt.Sprintf("https://static-cdn.jtvnw.net/emoticons/v2/%s/default/dark/1.0", emoteID),
+					ID:       emoteID,
+					Name:     emoteName,
+					URL:      fmt.Sprintf("https://static-cdn.jtvnw.net/emoticons/v2/%s/default/dark/1.0", emoteID),
+					Provider: "twitch", // the emotes tag doesn't say whose emote it is, so no scope
+					Width:    twitchEmoteSize,
+					Height:   twitchEmoteSize,
 					Positions: []EmotePosition{{
 						Start: start,
 						End:   end,
@@ -198,12 +230,17 @@ func ParseEmotes(msg *Message) []EmoteInfo {
 
 		if start < len(runes) && end >= start {
 			word := string(runes[start : end+1])
-			if emote, found := findEmote(msg.Channel, word); found {
+			if emote, found := a.findEmote(msg.Channel, word); found && !a.config.EmoteBlacklist[word] {
+				width, height := emoteSize(emote.FilePath)
 				emotes = append(emotes, EmoteInfo{
 					ID:       emote.ID,
 					Name:     word,
 					URL:      emote.URL,
 					FilePath: emote.FilePath,
+					Provider: emote.Provider,
+					Scope:    emote.Scope,
+					Width:    width,
+					Height:   height,
 					Positions: []EmotePosition{{
 						Start: start,
 						End:   end,
@@ -220,29 +257,60 @@ func ParseEmotes(msg *Message) []EmoteInfo {
 	return emotes
 }
 
-// ProcessMessageEmotes processes all emotes in a message
-func ProcessMessageEmotes(msg *Message) error {
-	emotes := ParseEmotes(msg)
-	if len(emotes) == 0 {
-		return nil
+// processMessageEmotes parses the emotes in a message and starts downloads
+// for any not on disk yet. The parsed emotes are returned for rendering;
+// ones being downloaded keep an empty FilePath, the finished download only
+// lands in the cache for later messages.
+func (a *App) processMessageEmotes(msg *Message) ([]EmoteInfo, error) {
+	if a.config.EmotesOff {
+		return nil, nil
 	}
+	emotes := a.parseEmotes(msg)
 
 	for _, emote := range emotes {
 		if emote.FilePath == "" {
-			go downloadEmote(emote, msg.Channel)
+			go a.downloadEmote(emote, msg.Channel)
 		}
 	}
 
-	return nil
+	return emotes, nil
+}
+
+// inFlight is a set of downloads in progress, keyed by file path
+type inFlight struct {
+	sync.Mutex
+	paths map[string]bool
+}
+
+// start reports whether the caller should download path, false if someone
+// already is
+func (f *inFlight) start(path string) bool {
+	f.Lock()
+	defer f.Unlock()
+	if f.paths[path] {
+		return false
+	}
+	f.paths[path] = true
+	return true
+}
+
+func (f *inFlight) finish(path string) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.paths, path)
 }
 
 // Emote downloader
-func downloadEmote(emote EmoteInfo, channelName string) {
-	channelDir := filepath.Join("channels", strings.TrimPrefix(channelName, "#"))
+func (a *App) downloadEmote(emote EmoteInfo, channelName string) {
+	if a.config.EmoteBlacklist[emote.Name] {
+		return
+	}
+
+	channelDir := a.dataPath("channels", strings.TrimPrefix(channelName, "#"))
 	emotesDir := filepath.Join(channelDir, "emotes")
 
 	if err := os.MkdirAll(emotesDir, 0755); err != nil {
-		log.Printf("Failed to create directories: %v\n", err)
+		logErrorf("Failed to create directories: %v\n", err)
 		return
 	}
 
@@ -256,147 +324,465 @@ func downloadEmote(emote EmoteInfo, channelName string) {
 	// Skip if already exists
 	if _, err := os.Stat(filePath); err == nil {
 		emote.FilePath = filePath
-		cacheEmote(emote)
+		a.cacheEmote(emote)
 		return
 	}
 
+	// A burst of messages with a new emote each land here, only the first
+	// downloads it
+	if !a.emoteDownloads.start(filePath) {
+		return
+	}
+	defer a.emoteDownloads.finish(filePath)
+
 	// Download the emote
+	var err error
+	defer func() { a.metrics.countDownload(err) }()
+
 	resp, err := http.Get(emote.URL)
 	if err != nil {
-		log.Printf("Failed to download emote %s: %v\n", emote.ID, err)
+		logWarnf("Failed to download emote %s: %v\n", emote.ID, err)
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Failed to download emote %s: status %d\n", emote.ID, resp.StatusCode)
+		err = &APIStatusError{API: "twitch emote", Code: resp.StatusCode}
+		logWarnf("Failed to download emote %s: status %d\n", emote.ID, resp.StatusCode)
 		return
 	}
 
-	file, err := os.Create(filePath)
+	err = writeAtomic(filePath, 0644, func(w io.Writer) error {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	})
 	if err != nil {
-		log.Printf("Failed to create file %s: %v\n", filePath, err)
+		logErrorf("Failed to write emote file %s: %v\n", filePath, err)
 		return
 	}
-	defer file.Close()
 
-	if _, err = io.Copy(file, resp.Body); err != nil {
-		log.Printf("Failed to write emote file %s: %v\n", filePath, err)
+	logDebugf("Downloaded emote: %s (%s) -> %s\n", emote.Name, emote.ID, filePath)
+	emote.FilePath = filePath
+	a.cacheEmote(emote)
+}
+
+// Emote cache keyed by emote ID, capped at $emotecache entries. The least
+// recently used entries are evicted, their files stay on disk and
+// getCachedEmote picks them up again on the next lookup.
+type emoteLRU struct {
+	sync.Mutex
+	capacity int
+	order    *list.List // front is most recently used
+	items    map[string]*list.Element
+}
+
+func newEmoteLRU(capacity int) *emoteLRU {
+	return &emoteLRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *emoteLRU) put(emote EmoteInfo) {
+	c.Lock()
+	defer c.Unlock()
+	if el, ok := c.items[emote.ID]; ok {
+		el.Value = emote
+		c.order.MoveToFront(el)
 		return
 	}
+	c.items[emote.ID] = c.order.PushFront(emote)
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(EmoteInfo).ID)
+	}
+}
 
-	log.Printf("Downloaded emote: %s (%s) -> %s\n", emote.Name, emote.ID, filePath)
-	emote.FilePath = filePath
-	cacheEmote(emote)
+func (c *emoteLRU) get(emoteID string) (EmoteInfo, bool) {
+	c.Lock()
+	defer c.Unlock()
+	el, ok := c.items[emoteID]
+	if !ok {
+		return EmoteInfo{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(EmoteInfo), true
 }
 
-// Simple emote cache
-var emoteCache = struct {
-	sync.RWMutex
-	emotes map[string]EmoteInfo
-}{emotes: make(map[string]EmoteInfo)}
+// evict drops every entry drop returns true for
+func (c *emoteLRU) evict(drop func(EmoteInfo) bool) {
+	c.Lock()
+	defer c.Unlock()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if emote := el.Value.(EmoteInfo); drop(emote) {
+			c.order.Remove(el)
+			delete(c.items, emote.ID)
+		}
+		el = next
+	}
+}
 
-func cacheEmote(emote EmoteInfo) {
-	emoteCache.Lock()
-	defer emoteCache.Unlock()
-	emoteCache.emotes[emote.ID] = emote
+func (a *App) cacheEmote(emote EmoteInfo) {
+	a.emoteCache.put(emote)
 }
 
-func getCachedEmote(emoteID string) (EmoteInfo, bool) {
-	emoteCache.RLock()
-	defer emoteCache.RUnlock()
-	emote, exists := emoteCache.emotes[emoteID]
-	return emote, exists
+func (a *App) getCachedEmote(emoteID string) (EmoteInfo, bool) {
+	if emote, ok := a.emoteCache.get(emoteID); ok {
+		return emote, true
+	}
+
+	// Not resident, look for a previously downloaded file
+	emote, ok := a.findEmoteOnDisk(emoteID)
+	if ok {
+		a.cacheEmote(emote)
+	}
+	return emote, ok
 }
 
-// ListEmotesInMessage returns emote information for a specific message
-func ListEmotesInMessage(msg *Message) []EmoteInfo {
-	return ParseEmotes(msg)
+// findEmoteOnDisk looks for a file written by downloadEmote in any channel
+func (a *App) findEmoteOnDisk(emoteID string) (EmoteInfo, bool) {
+	suffix := "_" + emoteID + ".png"
+	matches, err := filepath.Glob(a.dataPath("channels", "*", "emotes", "*"+suffix))
+	if err != nil || len(matches) == 0 {
+		return EmoteInfo{}, false
+	}
+
+	name := strings.TrimSuffix(filepath.Base(matches[0]), suffix)
+	if name == "emote" {
+		name = ""
+	}
+	return EmoteInfo{
+		ID:       emoteID,
+		Name:     name,
+		URL:      fmt.Sprintf("https://static-cdn.jtvnw.net/emoticons/v2/%s/default/dark/1.0", emoteID),
+		FilePath: matches[0],
+		Provider: "twitch",
+	}, true
 }
 
-// GetCachedEmotes returns all cached emotes
-func GetCachedEmotes() map[string]EmoteInfo {
-	emoteCache.RLock()
-	defer emoteCache.RUnlock()
-	result := make(map[string]EmoteInfo)
-	for k, v := range emoteCache.emotes {
-		result[k] = v
+// listEmotesInMessage returns emote information for a specific message
+func (a *App) listEmotesInMessage(msg *Message) []EmoteInfo {
+	return a.parseEmotes(msg)
+}
+
+// cachedEmotes returns the emotes currently held in the cache
+func (a *App) cachedEmotes() map[string]EmoteInfo {
+	a.emoteCache.Lock()
+	defer a.emoteCache.Unlock()
+	result := make(map[string]EmoteInfo, len(a.emoteCache.items))
+	for k, el := range a.emoteCache.items {
+		result[k] = el.Value.(EmoteInfo)
 	}
 	return result
 }
 
-// GetEmoteFilePath returns the local file path for an emote ID
-func GetEmoteFilePath(emoteID string) (string, bool) {
-	emote, exists := getCachedEmote(emoteID)
+// emoteCacheSizes counts the emotes held in memory per source
+func (a *App) emoteCacheSizes() map[string]int {
+	sizes := make(map[string]int)
+
+	a.emoteCache.Lock()
+	sizes["twitch"] = len(a.emoteCache.items)
+	a.emoteCache.Unlock()
+
+	global7TVMutex.RLock()
+	sizes["7tvGlobal"] = len(global7TVEmotes)
+	global7TVMutex.RUnlock()
+
+	globalBTTVMutex.RLock()
+	sizes["bttvGlobal"] = len(globalBTTVEmotes)
+	globalBTTVMutex.RUnlock()
+
+	globalFFZMutex.RLock()
+	sizes["ffzGlobal"] = len(globalFFZEmotes)
+	globalFFZMutex.RUnlock()
+
+	channelsMutex.RLock()
+	for _, channel := range channels {
+		sizes["7tvChannel"] += len(channel.Emotes)
+	}
+	channelsMutex.RUnlock()
+
+	channelsBTTVMutex.RLock()
+	for _, emotes := range channelsBTTV {
+		sizes["bttvChannel"] += len(emotes)
+	}
+	channelsBTTVMutex.RUnlock()
+
+	channelsFFZMutex.RLock()
+	for _, emotes := range channelsFFZ {
+		sizes["ffzChannel"] += len(emotes)
+	}
+	channelsFFZMutex.RUnlock()
+
+	return sizes
+}
+
+// emoteDiskUsage returns the bytes under channels/<name> for each channel
+// directory, including "global"
+func (a *App) emoteDiskUsage() (map[string]int64, error) {
+	root := a.dataPath("channels")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]int64{}, nil
+		}
+		return nil, err
+	}
+
+	usage := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var total int64
+		filepath.WalkDir(filepath.Join(root, entry.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+			return nil
+		})
+		usage[entry.Name()] = total
+	}
+	return usage, nil
+}
+
+// clearChannelEmotes deletes channels/<name> and forgets its emotes. Maps are
+// emptied rather than removed since a fetch may be writing into them.
+func (a *App) clearChannelEmotes(channelName string) error {
+	dir := a.dataPath("channels", channelName)
+
+	channelsMutex.Lock()
+	if channel, ok := channels[channelName]; ok {
+		channel.Emotes = make(map[string]EmoteInfo)
+		channels[channelName] = channel
+	}
+	channelsMutex.Unlock()
+
+	channelsBTTVMutex.Lock()
+	if _, ok := channelsBTTV[channelName]; ok {
+		channelsBTTV[channelName] = make(map[string]EmoteInfo)
+	}
+	channelsBTTVMutex.Unlock()
+
+	channelsFFZMutex.Lock()
+	if _, ok := channelsFFZ[channelName]; ok {
+		channelsFFZ[channelName] = make(map[string]EmoteInfo)
+	}
+	channelsFFZMutex.Unlock()
+
+	a.emoteCache.evict(func(emote EmoteInfo) bool {
+		return strings.HasPrefix(emote.FilePath, dir+string(filepath.Separator))
+	})
+
+	return os.RemoveAll(dir)
+}
+
+// clearGlobalEmotes deletes channels/global and empties the global sets
+func (a *App) clearGlobalEmotes() error {
+	global7TVMutex.Lock()
+	clear(global7TVEmotes)
+	global7TVMutex.Unlock()
+
+	globalBTTVMutex.Lock()
+	clear(globalBTTVEmotes)
+	globalBTTVMutex.Unlock()
+
+	globalFFZMutex.Lock()
+	clear(globalFFZEmotes)
+	globalFFZMutex.Unlock()
+
+	return os.RemoveAll(a.dataPath("channels", "global"))
+}
+
+// emoteFilePath returns the local file path for an emote ID
+func (a *App) emoteFilePath(emoteID string) (string, bool) {
+	emote, exists := a.getCachedEmote(emoteID)
 	if !exists {
 		return "", false
 	}
 	return emote.FilePath, true
 }
 
-// Existing helper functions remain mostly the same
-func downloadFirstFrameFromGIF(url, outPath string) error {
+// downloadEmoteImage tries each candidate URL in order (largest first) and
+// stores the first one that downloads and decodes as a PNG at outputPath,
+// resized to MaxEmoteSize. Returns the URL that worked.
+func (a *App) downloadEmoteImage(candidates []string, outputPath string) (string, error) {
+	var lastErr error
+	for _, url := range candidates {
+		err := saveEmoteImage(url, outputPath)
+		a.metrics.countDownload(err)
+		if err == nil {
+			return url, nil
+		}
+		os.Remove(outputPath)
+		lastErr = err
+		logDebugf("Emote download from %s failed, trying next size: %v\n", url, err)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no image URLs for %s", outputPath)
+	}
+	return "", lastErr
+}
+
+// saveEmoteImage downloads a single emote image. GIFs are reduced to their
+// first frame, everything else is stored as-is before resizing.
+func saveEmoteImage(url, outputPath string) error {
 	resp, err := http.Get(url)
 	if err != nil {
-		return fmt.Errorf("error downloading gif: %w", err)
+		return fmt.Errorf("%w: %w", ErrConnect, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status downloading gif: %d", resp.StatusCode)
+		return &APIStatusError{API: url, Code: resp.StatusCode}
 	}
 
-	g, err := gif.Decode(resp.Body)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error decoding gif: %w", err)
+		return err
 	}
 
-	outFile, err := os.Create(outPath)
-	if err != nil {
-		return fmt.Errorf("error creating png file: %w", err)
+	// Only the first frame of animated images is kept
+	var frame image.Image
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		frame, err = gif.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("error decoding gif: %w: %w", ErrDecode, err)
+		}
+	case isWebP(data):
+		// x/image/webp can't decode animated webp, the caller moves on to
+		// the next candidate in that case
+		frame, err = webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("error decoding webp: %w: %w", ErrDecode, err)
+		}
+	}
+	if frame != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, frame); err != nil {
+			return fmt.Errorf("error encoding png: %w", err)
+		}
+		data = buf.Bytes()
 	}
-	defer outFile.Close()
 
-	if err := png.Encode(outFile, g); err != nil {
-		return fmt.Errorf("error encoding png: %w", err)
+	if err := writeFileAtomic(outputPath, data, 0644); err != nil {
+		return err
 	}
+	return resizeImageToMax32(outputPath)
+}
 
-	return nil
+func isWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP"
 }
 
-func downloadFile(url, filepath string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
+func bttvImageURLs(id string) []string {
+	return []string{
+		fmt.Sprintf("https://cdn.betterttv.net/emote/%s/3x", id),
+		fmt.Sprintf("https://cdn.betterttv.net/emote/%s/2x", id),
+		fmt.Sprintf("https://cdn.betterttv.net/emote/%s/1x", id),
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status: %d", resp.StatusCode)
+func ffzImageURLs(urls map[string]string) []string {
+	var result []string
+	for _, size := range []string{"4", "2", "1"} {
+		url, ok := urls[size]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(url, "//") {
+			url = "https:" + url
+		}
+		result = append(result, url)
 	}
+	return result
+}
 
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
+// sevenTVImageURLs picks the PNG, then GIF, then WEBP files. Within each
+// format the preferred scale ($7tvscale) comes first, then larger ones, then
+// smaller; largest first when 0. 7TV lists files smallest first (1x.png, 2x.png, ...).
+func sevenTVImageURLs(hostURL string, fileNames []string, preferred int) []string {
+	fileNames = append([]string(nil), fileNames...)
+	sort.SliceStable(fileNames, func(i, j int) bool {
+		return sevenTVScaleRank(fileNames[i], preferred) < sevenTVScaleRank(fileNames[j], preferred)
+	})
+
+	var pngs, gifs, webps []string
+	for i := range fileNames {
+		url := "https:" + hostURL + "/" + fileNames[i]
+		switch {
+		case strings.HasSuffix(fileNames[i], ".png"):
+			pngs = append(pngs, url)
+		case strings.HasSuffix(fileNames[i], ".gif"):
+			gifs = append(gifs, url)
+		case strings.HasSuffix(fileNames[i], ".webp"):
+			webps = append(webps, url)
+		}
 	}
-	defer out.Close()
+	return append(append(pngs, gifs...), webps...)
+}
 
-	_, err = io.Copy(out, resp.Body)
+// sevenTVScaleRank orders a 7TV file name like "2x.webp" by how close it is
+// to the preferred scale, lower is better
+func sevenTVScaleRank(name string, preferred int) int {
+	scale, err := strconv.Atoi(strings.SplitN(name, "x", 2)[0])
 	if err != nil {
-		return err
+		return 100 // unknown naming, last resort
+	}
+	switch {
+	case preferred == 0:
+		return -scale
+	case scale >= preferred:
+		return scale - preferred
+	default:
+		// downscaling a bigger image beats upscaling a smaller one
+		return 10 + preferred - scale
 	}
-	return resizeImageToMax32(filepath)
 }
 
 const MaxEmoteSize = 32
 
-func resizeImageToMax32(path string) error {
+// Twitch emotes are stored as the 1.0 image, always this size
+const twitchEmoteSize = 28
+
+// Display sizes of stored emote images by path, [2]int{width, height}.
+// Recorded when an image is written, read from the file header otherwise.
+var emoteSizes sync.Map
+
+func emoteSize(path string) (int, int) {
+	if path == "" {
+		return 0, 0
+	}
+	if size, ok := emoteSizes.Load(path); ok {
+		return size.([2]int)[0], size.([2]int)[1]
+	}
 	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return 0, 0
 	}
 	defer f.Close()
-	img, _, err := image.Decode(f)
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0
+	}
+	emoteSizes.Store(path, [2]int{config.Width, config.Height})
+	return config.Width, config.Height
+}
+
+func resizeImageToMax32(path string) error {
+	// read it all up front, Windows can't rename over a file that's open
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
@@ -407,6 +793,7 @@ func resizeImageToMax32(path string) error {
 
 	// Only resize if height exceeds MaxEmoteSize
 	if height <= MaxEmoteSize {
+		emoteSizes.Store(path, [2]int{width, height})
 		return nil
 	}
 
@@ -418,33 +805,49 @@ func resizeImageToMax32(path string) error {
 	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
 	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
 
-	outFile, err := os.Create(path)
+	err = writeAtomic(path, 0644, func(w io.Writer) error {
+		return png.Encode(w, dst)
+	})
 	if err != nil {
 		return err
 	}
-	defer outFile.Close()
+	emoteSizes.Store(path, [2]int{newWidth, newHeight})
+	return nil
+}
 
-	return png.Encode(outFile, dst)
+// 7TV emote flags (emote.data.flags), names as used by $7tvexclude
+var sevenTVFlags = map[string]int{
+	"private":          1 << 0,
+	"zerowidth":        1 << 8,
+	"nsfw":             1 << 16,
+	"epilepsy":         1 << 17,
+	"edgy":             1 << 18,
+	"twitchdisallowed": 1 << 24,
 }
 
-func Fetch7TVEmotes(twitchUserID, channelName string) error {
+func (a *App) fetch7TVEmotes(twitchUserID, channelName string) error {
 	url := fmt.Sprintf("https://7tv.io/v3/users/twitch/%s", twitchUserID)
-	resp, err := http.Get(url)
+	resp, err := a.metrics.apiGet(url)
 	if err != nil {
-		return fmt.Errorf("failed to fetch 7TV emotes: %w", err)
+		return fmt.Errorf("failed to fetch 7TV emotes: %w: %w", ErrConnect, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		logDebugf("7TV: %s has no 7TV account\n", channelName)
+		return nil // Not an error, just no emotes
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("7TV API error: %d", resp.StatusCode)
+		return &APIStatusError{API: "7TV", Code: resp.StatusCode}
 	}
 
 	var apiResp struct {
-		EmoteSet struct {
+		EmoteSet *struct {
 			Emotes []struct {
 				ID   string `json:"id"`
 				Name string `json:"name"`
 				Data struct {
-					Host struct {
+					Flags int `json:"flags"`
+					Host  struct {
 						URL   string `json:"url"`
 						Files []struct {
 							Name   string `json:"name"`
@@ -457,12 +860,18 @@ func Fetch7TVEmotes(twitchUserID, channelName string) error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return fmt.Errorf("failed to decode 7TV JSON: %w", err)
+		return fmt.Errorf("failed to decode 7TV JSON: %w: %w", ErrDecode, err)
+	}
+
+	// Linked accounts without an active set get a null emote_set
+	if apiResp.EmoteSet == nil || len(apiResp.EmoteSet.Emotes) == 0 {
+		logDebugf("7TV: %s has no active emote set\n", channelName)
+		return nil
 	}
 
 	// log.Printf("channel 7tv emotes: %+v\n", apiResp)
 
-	channelDir := filepath.Join("channels", strings.TrimPrefix(channelName, "#"))
+	channelDir := a.dataPath("channels", strings.TrimPrefix(channelName, "#"))
 	emoteDir := filepath.Join(channelDir, "emotes_7tv")
 
 	if err := os.MkdirAll(emoteDir, 0755); err != nil {
@@ -481,24 +890,33 @@ func Fetch7TVEmotes(twitchUserID, channelName string) error {
 	}
 	channelsMutex.Unlock()
 
-	for _, emote := range apiResp.EmoteSet.Emotes {
-		var imageURL, sourceFormat string
+	kept := 0
+	for i, emote := range apiResp.EmoteSet.Emotes {
+		if a.config.EmoteBlacklist[emote.Name] {
+			continue
+		}
+		if emote.Data.Flags&a.config.SevenTVExclude != 0 {
+			logDebugf("Skipping 7TV emote %s for %s, excluded flags %#x\n", emote.Name, normalizedChannelName, emote.Data.Flags&a.config.SevenTVExclude)
+			continue
+		}
+		if a.config.SevenTVMax > 0 && kept >= a.config.SevenTVMax {
+			logDebugf("7TV emote cap (%d) reached for %s, skipping the remaining %d\n",
+				a.config.SevenTVMax, normalizedChannelName, len(apiResp.EmoteSet.Emotes)-i)
+			break
+		}
+		kept++
 
+		fileNames := make([]string, 0, len(emote.Data.Host.Files))
 		for _, file := range emote.Data.Host.Files {
-			if strings.HasSuffix(file.Name, ".png") {
-				imageURL = "https:" + emote.Data.Host.URL + "/" + file.Name
-				sourceFormat = "png"
-
-			} else if strings.HasSuffix(file.Name, ".gif") && imageURL == "" {
-				imageURL = "https:" + emote.Data.Host.URL + "/" + file.Name
-				sourceFormat = "gif"
-			}
+			fileNames = append(fileNames, file.Name)
 		}
+		candidates := sevenTVImageURLs(emote.Data.Host.URL, fileNames, a.config.SevenTVScale)
 
-		if imageURL == "" {
-			log.Printf("No PNG or GIF found for emote %s, skipping\n", emote.Name)
+		if len(candidates) == 0 {
+			logDebugf("No PNG, GIF or WEBP found for emote %s, skipping\n", emote.Name)
 			continue
 		}
+		imageURL := candidates[0]
 
 		outputPath := filepath.Join(emoteDir, fmt.Sprintf("%s_%s.png", emote.Name, emote.ID))
 
@@ -512,26 +930,20 @@ func Fetch7TVEmotes(twitchUserID, channelName string) error {
 				Name:     emote.Name,
 				ImageURL: imageURL,
 				FilePath: outputPath,
+				Provider: "7tv",
+				Scope:    "channel",
 			}
 			channelsMutex.Unlock()
 			continue
 		}
 
-		if sourceFormat == "png" {
-			err := downloadFile(imageURL, outputPath)
-			if err != nil {
-				log.Printf("Failed to download 7TV emote (png) %s: %v\n", emote.Name, err)
-				continue
-			}
-		} else if sourceFormat == "gif" {
-			err := downloadFirstFrameFromGIF(imageURL, outputPath)
-			if err != nil {
-				log.Printf("Failed to convert GIF emote %s: %v\n", emote.Name, err)
-				continue
-			}
+		imageURL, err := a.downloadEmoteImage(candidates, outputPath)
+		if err != nil {
+			logWarnf("Failed to download 7TV emote %s: %v\n", emote.Name, err)
+			continue
 		}
 
-		log.Printf("Downloaded 7TV emote: %s -> %s\n", emote.Name, outputPath)
+		logDebugf("Downloaded 7TV emote: %s -> %s\n", emote.Name, outputPath)
 
 		channelsMutex.Lock()
 		channels[normalizedChannelName].Emotes[emote.Name] = EmoteInfo{
@@ -540,6 +952,8 @@ func Fetch7TVEmotes(twitchUserID, channelName string) error {
 			ImageURL: imageURL,
 			FilePath: outputPath,
 			URL:      imageURL,
+			Provider: "7tv",
+			Scope:    "channel",
 		}
 		channelsMutex.Unlock()
 	}
@@ -547,18 +961,33 @@ func Fetch7TVEmotes(twitchUserID, channelName string) error {
 	return nil
 }
 
-func Fetch7TVGlobalEmotes() error {
-	log.Println("inside fetch global")
-	log.Println(global7TVEmotes)
+// fetchGlobalEmotes loads the global 7TV, BTTV and FFZ sets, once on startup
+func (a *App) fetchGlobalEmotes() {
+	if a.config.EmotesOff {
+		logInfof("Emotes are off, not fetching any")
+		return
+	}
+	if err := a.fetch7TVGlobalEmotes(); err != nil {
+		logWarnf("failed to fetch 7TV global emotes: %v", err)
+	}
+	if err := a.fetchBTTVGlobalEmotes(); err != nil {
+		logWarnf("failed to fetch BTTV global emotes: %v", err)
+	}
+	if err := a.fetchFFZGlobalEmotes(); err != nil {
+		logWarnf("failed to fetch FFZ global emotes: %v", err)
+	}
+}
+
+func (a *App) fetch7TVGlobalEmotes() error {
 	url := "https://7tv.io/v3/emote-sets/global"
-	resp, err := http.Get(url)
+	resp, err := a.metrics.apiGet(url)
 	if err != nil {
-		return fmt.Errorf("failed to fetch 7TV global emotes: %w", err)
+		return fmt.Errorf("failed to fetch 7TV global emotes: %w: %w", ErrConnect, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("7TV global API error: %d", resp.StatusCode)
+		return &APIStatusError{API: "7TV global", Code: resp.StatusCode}
 	}
 
 	var data struct {
@@ -578,68 +1007,79 @@ func Fetch7TVGlobalEmotes() error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return fmt.Errorf("failed to decode global emotes JSON: %w", err)
+		return fmt.Errorf("failed to decode global emotes JSON: %w: %w", ErrDecode, err)
 	}
 
-	emoteDir := filepath.Join("channels", "global", "emotes_7tv")
+	emoteDir := a.dataPath("channels", "global", "emotes_7tv")
 	if err := os.MkdirAll(emoteDir, 0755); err != nil {
 		return fmt.Errorf("failed to create global emote directory: %w", err)
 	}
 
+	// Built unlocked (downloads are slow) and merged in at the end, chat
+	// reads the global maps the whole time
+	fetched := make(map[string]EmoteInfo, len(data.Emotes))
 	for _, emote := range data.Emotes {
+		if a.config.EmoteBlacklist[emote.Name] {
+			continue
+		}
 		// Select .png or .gif
-		var imageURL, sourceFormat string
+		fileNames := make([]string, 0, len(emote.Data.Host.Files))
 		for _, file := range emote.Data.Host.Files {
-			if strings.HasSuffix(file.Name, ".png") {
-				imageURL = "https:" + emote.Data.Host.URL + "/" + file.Name
-				sourceFormat = "png"
-				break
-			} else if strings.HasSuffix(file.Name, ".gif") && imageURL == "" {
-				imageURL = "https:" + emote.Data.Host.URL + "/" + file.Name
-				sourceFormat = "gif"
-			}
+			fileNames = append(fileNames, file.Name)
 		}
+		candidates := sevenTVImageURLs(emote.Data.Host.URL, fileNames, a.config.SevenTVScale)
 
-		if imageURL == "" {
+		if len(candidates) == 0 {
 			continue
 		}
+		imageURL := candidates[0]
 
 		outputPath := filepath.Join(emoteDir, fmt.Sprintf("%s_%s.png", emote.Name, emote.ID))
 
 		if _, err := os.Stat(outputPath); err == nil {
-			global7TVEmotes[emote.Name] = EmoteInfo{
+			fetched[emote.Name] = EmoteInfo{
 				ID:       emote.ID,
 				Name:     emote.Name,
 				ImageURL: imageURL,
 				FilePath: outputPath,
+				Provider: "7tv",
+				Scope:    "global",
 			}
 			continue
 		}
 
-		if sourceFormat == "png" {
-			_ = downloadFile(imageURL, outputPath)
-		} else if sourceFormat == "gif" {
-			_ = downloadFirstFrameFromGIF(imageURL, outputPath)
+		imageURL, err := a.downloadEmoteImage(candidates, outputPath)
+		if err != nil {
+			logWarnf("Failed to download 7TV global emote %s: %v\n", emote.Name, err)
+			continue
 		}
 
-		global7TVEmotes[emote.Name] = EmoteInfo{
+		fetched[emote.Name] = EmoteInfo{
 			ID:       emote.ID,
 			Name:     emote.Name,
 			ImageURL: imageURL,
 			FilePath: outputPath,
+			Provider: "7tv",
+			Scope:    "global",
 		}
 	}
 
+	global7TVMutex.Lock()
+	maps.Copy(global7TVEmotes, fetched)
+	global7TVMutex.Unlock()
 	return nil
 }
 
-func FetchBTTVGlobalEmotes() error {
+func (a *App) fetchBTTVGlobalEmotes() error {
 	url := "https://api.betterttv.net/3/cached/emotes/global"
-	resp, err := http.Get(url)
+	resp, err := a.metrics.apiGet(url)
 	if err != nil {
-		return fmt.Errorf("failed to fetch BTTV global emotes: %w", err)
+		return fmt.Errorf("failed to fetch BTTV global emotes: %w: %w", ErrConnect, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &APIStatusError{API: "BTTV global", Code: resp.StatusCode}
+	}
 
 	var emotes []struct {
 		ID   string `json:"id"`
@@ -647,45 +1087,57 @@ func FetchBTTVGlobalEmotes() error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&emotes); err != nil {
-		return fmt.Errorf("failed to decode BTTV global emotes JSON: %w", err)
+		return fmt.Errorf("failed to decode BTTV global emotes JSON: %w: %w", ErrDecode, err)
 	}
 
-	emoteDir := filepath.Join("channels", "global", "emotes_bttv")
+	emoteDir := a.dataPath("channels", "global", "emotes_bttv")
 	if err := os.MkdirAll(emoteDir, 0755); err != nil {
 		return fmt.Errorf("failed to create BTTV global emote directory: %w", err)
 	}
 
+	fetched := make(map[string]EmoteInfo, len(emotes))
 	for _, emote := range emotes {
-		imageURL := fmt.Sprintf("https://cdn.betterttv.net/emote/%s/3x", emote.ID)
+		if a.config.EmoteBlacklist[emote.Code] {
+			continue
+		}
+		candidates := bttvImageURLs(emote.ID)
+		imageURL := candidates[0]
 		outputPath := filepath.Join(emoteDir, fmt.Sprintf("%s_%s.png", emote.Code, emote.ID))
 
 		if _, err := os.Stat(outputPath); err != nil {
-			if err := downloadFile(imageURL, outputPath); err != nil {
-				log.Printf("Failed to download BTTV emote %s: %v\n", emote.Code, err)
+			imageURL, err = a.downloadEmoteImage(candidates, outputPath)
+			if err != nil {
+				logWarnf("Failed to download BTTV emote %s: %v\n", emote.Code, err)
 				continue
 			}
-			if err := resizeImageToMax32(outputPath); err != nil {
-				log.Printf("Failed to resize BTTV emote %s: %v\n", emote.Code, err)
-			}
 		}
 
-		globalBTTVEmotes[emote.Code] = EmoteInfo{
+		fetched[emote.Code] = EmoteInfo{
 			ID:       emote.ID,
 			Name:     emote.Code,
 			ImageURL: imageURL,
 			FilePath: outputPath,
+			Provider: "bttv",
+			Scope:    "global",
 		}
 	}
+
+	globalBTTVMutex.Lock()
+	maps.Copy(globalBTTVEmotes, fetched)
+	globalBTTVMutex.Unlock()
 	return nil
 }
 
-func FetchBTTVChannelEmotes(channelID, channelName string) error {
+func (a *App) fetchBTTVChannelEmotes(channelID, channelName string) error {
 	url := fmt.Sprintf("https://api.betterttv.net/3/cached/users/twitch/%s", channelID)
-	resp, err := http.Get(url)
+	resp, err := a.metrics.apiGet(url)
 	if err != nil {
-		return fmt.Errorf("failed to fetch BTTV emotes for channel %s: %w", channelName, err)
+		return fmt.Errorf("failed to fetch BTTV emotes for channel %s: %w: %w", channelName, ErrConnect, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &APIStatusError{API: "BTTV " + channelName, Code: resp.StatusCode}
+	}
 
 	var data struct {
 		ChannelEmotes []struct {
@@ -699,10 +1151,10 @@ func FetchBTTVChannelEmotes(channelID, channelName string) error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return fmt.Errorf("failed to decode BTTV channel emotes JSON: %w", err)
+		return fmt.Errorf("failed to decode BTTV channel emotes JSON: %w: %w", ErrDecode, err)
 	}
 
-	emoteDir := filepath.Join("channels", strings.TrimPrefix(channelName, "#"), "emotes_bttv")
+	emoteDir := a.dataPath("channels", strings.TrimPrefix(channelName, "#"), "emotes_bttv")
 	if err := os.MkdirAll(emoteDir, 0755); err != nil {
 		return fmt.Errorf("failed to create BTTV emote directory: %w", err)
 	}
@@ -717,29 +1169,19 @@ func FetchBTTVChannelEmotes(channelID, channelName string) error {
 	}
 
 	for _, emote := range append(data.ChannelEmotes, data.SharedEmotes...) {
-		imageURL := fmt.Sprintf("https://cdn.betterttv.net/emote/%s/3x", emote.ID)
+		if a.config.EmoteBlacklist[emote.Code] {
+			continue
+		}
+		candidates := bttvImageURLs(emote.ID)
+		imageURL := candidates[0]
 		outputPath := filepath.Join(emoteDir, fmt.Sprintf("%s_%s.png", emote.Code, emote.ID))
 
 		if _, err := os.Stat(outputPath); err != nil {
-			headResp, err := http.Head(imageURL)
-			if err != nil {
-				log.Printf("Failed HEAD request for %s: %v\n", emote.Code, err)
-				continue
-			}
-			contentType := headResp.Header.Get("Content-Type")
-			if strings.Contains(contentType, "gif") {
-				err = downloadFirstFrameFromGIF(imageURL, outputPath)
-			} else {
-				err = downloadFile(imageURL, outputPath)
-			}
+			imageURL, err = a.downloadEmoteImage(candidates, outputPath)
 			if err != nil {
-				log.Printf("Failed to download BTTV emote %s: %v\n", emote.Code, err)
+				logWarnf("Failed to download BTTV emote %s: %v\n", emote.Code, err)
 				continue
 			}
-
-			if err := resizeImageToMax32(outputPath); err != nil {
-				log.Printf("Failed to resize BTTV emote %s: %v\n", emote.Code, err)
-			}
 		}
 
 		// Directly update the global map, which is now locked
@@ -748,21 +1190,23 @@ func FetchBTTVChannelEmotes(channelID, channelName string) error {
 			Name:     emote.Code,
 			ImageURL: imageURL,
 			FilePath: outputPath,
+			Provider: "bttv",
+			Scope:    "channel",
 		}
 	}
 	return nil
 }
 
-func FetchFFZGlobalEmotes() error {
+func (a *App) fetchFFZGlobalEmotes() error {
 	url := "https://api.frankerfacez.com/v1/set/global"
-	resp, err := http.Get(url)
+	resp, err := a.metrics.apiGet(url)
 	if err != nil {
-		return fmt.Errorf("failed to fetch FFZ global emotes: %w", err)
+		return fmt.Errorf("failed to fetch FFZ global emotes: %w: %w", ErrConnect, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("FFZ global API error: %d", resp.StatusCode)
+		return &APIStatusError{API: "FFZ global", Code: resp.StatusCode}
 	}
 
 	var data struct {
@@ -778,112 +1222,88 @@ func FetchFFZGlobalEmotes() error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return fmt.Errorf("failed to decode FFZ global emotes JSON: %w", err)
+		return fmt.Errorf("failed to decode FFZ global emotes JSON: %w: %w", ErrDecode, err)
 	}
 
-	emoteDir := filepath.Join("channels", "global", "emotes_ffz")
+	emoteDir := a.dataPath("channels", "global", "emotes_ffz")
 	if err := os.MkdirAll(emoteDir, 0755); err != nil {
 		return fmt.Errorf("failed to create FFZ global emote directory: %w", err)
 	}
 
+	fetched := make(map[string]EmoteInfo)
 	for _, set := range data.Sets {
 		for _, emote := range set.Emoticons {
+			if a.config.EmoteBlacklist[emote.Name] {
+				continue
+			}
 			// Prefer larger sizes: 4, 2, then 1
-			var imageURL string
-			if url, ok := emote.URLs["4"]; ok {
-				if strings.HasPrefix(url, "//") {
-					imageURL = "https:" + url
-				} else {
-					imageURL = url
-				}
-			} else if url, ok := emote.URLs["2"]; ok {
-				if strings.HasPrefix(url, "//") {
-					imageURL = "https:" + url
-				} else {
-					imageURL = url
-				}
-			} else if url, ok := emote.URLs["1"]; ok {
-				if strings.HasPrefix(url, "//") {
-					imageURL = "https:" + url
-				} else {
-					imageURL = url
-				}
-			} else {
-				log.Printf("No valid URL found for FFZ global emote %s, skipping\n", emote.Name)
+			candidates := ffzImageURLs(emote.URLs)
+			if len(candidates) == 0 {
+				logDebugf("No valid URL found for FFZ global emote %s, skipping\n", emote.Name)
 				continue
 			}
+			imageURL := candidates[0]
 
 			outputPath := filepath.Join(emoteDir, fmt.Sprintf("%s_%d.png", emote.Name, emote.ID))
 
 			// Skip if already exists
 			if _, err := os.Stat(outputPath); err == nil {
-				globalFFZEmotes[emote.Name] = EmoteInfo{
+				fetched[emote.Name] = EmoteInfo{
 					ID:       fmt.Sprintf("%d", emote.ID),
 					Name:     emote.Name,
 					ImageURL: imageURL,
 					FilePath: outputPath,
+					Provider: "ffz",
+					Scope:    "global",
 				}
 				continue
 			}
 
-			// Download the emote - check if it's a GIF first
-			headResp, err := http.Head(imageURL)
+			imageURL, err := a.downloadEmoteImage(candidates, outputPath)
 			if err != nil {
-				log.Printf("Failed HEAD request for FFZ global emote %s: %v\n", emote.Name, err)
+				logWarnf("Failed to download FFZ global emote %s: %v\n", emote.Name, err)
 				continue
 			}
-			contentType := headResp.Header.Get("Content-Type")
-
-			if strings.Contains(contentType, "gif") {
-				err = downloadFirstFrameFromGIF(imageURL, outputPath)
-			} else {
-				err = downloadFile(imageURL, outputPath)
-			}
 
-			if err != nil {
-				log.Printf("Failed to download FFZ global emote %s: %v\n", emote.Name, err)
-				continue
-			}
+			logDebugf("Downloaded FFZ global emote: %s -> %s\n", emote.Name, outputPath)
 
-			// Resize if needed
-			if err := resizeImageToMax32(outputPath); err != nil {
-				log.Printf("Failed to resize FFZ global emote %s: %v\n", emote.Name, err)
-			}
-
-			log.Printf("Downloaded FFZ global emote: %s -> %s\n", emote.Name, outputPath)
-
-			globalFFZEmotes[emote.Name] = EmoteInfo{
+			fetched[emote.Name] = EmoteInfo{
 				ID:       fmt.Sprintf("%d", emote.ID),
 				Name:     emote.Name,
 				ImageURL: imageURL,
 				FilePath: outputPath,
+				Provider: "ffz",
+				Scope:    "global",
 			}
 		}
 	}
 
+	globalFFZMutex.Lock()
+	maps.Copy(globalFFZEmotes, fetched)
+	globalFFZMutex.Unlock()
 	return nil
 }
 
-func FetchFFZChannelEmotes(channelID, channelName string) error {
+func (a *App) fetchFFZChannelEmotes(channelID, channelName string) error {
 	// FFZ API uses channel name (username) instead of numeric ID
 	username := strings.TrimPrefix(channelName, "#")
-	log.Printf("Fetching FFZ emotes for channel %s (username: %s)\n", channelName, username)
+	logDebugf("Fetching FFZ emotes for channel %s (username: %s)\n", channelName, username)
 
 	url := fmt.Sprintf("https://api.frankerfacez.com/v1/room/%s", username)
-	resp, err := http.Get(url)
+	resp, err := a.metrics.apiGet(url)
 	if err != nil {
-		return fmt.Errorf("failed to fetch FFZ emotes for channel %s: %w", channelName, err)
+		return fmt.Errorf("failed to fetch FFZ emotes for channel %s: %w: %w", channelName, ErrConnect, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		log.Printf("FFZ: Channel %s not found or has no FFZ emotes\n", username)
+		logDebugf("FFZ: Channel %s not found or has no FFZ emotes\n", username)
 		return nil // Not an error, just no emotes
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("FFZ channel API returned status %d for channel %s\n", resp.StatusCode, channelName)
-		return fmt.Errorf("FFZ channel API error for %s: %d", channelName, resp.StatusCode)
+		logWarnf("FFZ channel API returned status %d for channel %s\n", resp.StatusCode, channelName)
+		return &APIStatusError{API: "FFZ " + channelName, Code: resp.StatusCode}
 	}
 
 	var data struct {
@@ -899,12 +1319,12 @@ func FetchFFZChannelEmotes(channelID, channelName string) error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return fmt.Errorf("failed to decode FFZ channel emotes JSON: %w", err)
+		return fmt.Errorf("failed to decode FFZ channel emotes JSON: %w: %w", ErrDecode, err)
 	}
 
-	log.Printf("FFZ API returned %d sets for channel %s\n", len(data.Sets), channelName)
+	logDebugf("FFZ API returned %d sets for channel %s\n", len(data.Sets), channelName)
 
-	emoteDir := filepath.Join("channels", strings.TrimPrefix(channelName, "#"), "emotes_ffz")
+	emoteDir := a.dataPath("channels", strings.TrimPrefix(channelName, "#"), "emotes_ffz")
 	if err := os.MkdirAll(emoteDir, 0755); err != nil {
 		return fmt.Errorf("failed to create FFZ emote directory: %w", err)
 	}
@@ -920,33 +1340,19 @@ func FetchFFZChannelEmotes(channelID, channelName string) error {
 
 	emoteCount := 0
 	for _, set := range data.Sets {
-		log.Printf("Processing FFZ set with %d emoticons\n", len(set.Emoticons))
+		logDebugf("Processing FFZ set with %d emoticons\n", len(set.Emoticons))
 		for _, emote := range set.Emoticons {
+			if a.config.EmoteBlacklist[emote.Name] {
+				continue
+			}
 			emoteCount++
 			// Prefer larger sizes: 4, 2, then 1
-			var imageURL string
-			if url, ok := emote.URLs["4"]; ok {
-				if strings.HasPrefix(url, "//") {
-					imageURL = "https:" + url
-				} else {
-					imageURL = url
-				}
-			} else if url, ok := emote.URLs["2"]; ok {
-				if strings.HasPrefix(url, "//") {
-					imageURL = "https:" + url
-				} else {
-					imageURL = url
-				}
-			} else if url, ok := emote.URLs["1"]; ok {
-				if strings.HasPrefix(url, "//") {
-					imageURL = "https:" + url
-				} else {
-					imageURL = url
-				}
-			} else {
-				log.Printf("No valid URL found for FFZ emote %s, skipping\n", emote.Name)
+			candidates := ffzImageURLs(emote.URLs)
+			if len(candidates) == 0 {
+				logDebugf("No valid URL found for FFZ emote %s, skipping\n", emote.Name)
 				continue
 			}
+			imageURL := candidates[0]
 
 			outputPath := filepath.Join(emoteDir, fmt.Sprintf("%s_%d.png", emote.Name, emote.ID))
 
@@ -957,45 +1363,31 @@ func FetchFFZChannelEmotes(channelID, channelName string) error {
 					Name:     emote.Name,
 					ImageURL: imageURL,
 					FilePath: outputPath,
+					Provider: "ffz",
+					Scope:    "channel",
 				}
 				continue
 			}
 
-			// Download the emote - check if it's a GIF first
-			headResp, err := http.Head(imageURL)
+			imageURL, err := a.downloadEmoteImage(candidates, outputPath)
 			if err != nil {
-				log.Printf("Failed HEAD request for FFZ emote %s: %v\n", emote.Name, err)
+				logWarnf("Failed to download FFZ emote %s: %v\n", emote.Name, err)
 				continue
 			}
-			contentType := headResp.Header.Get("Content-Type")
-
-			if strings.Contains(contentType, "gif") {
-				err = downloadFirstFrameFromGIF(imageURL, outputPath)
-			} else {
-				err = downloadFile(imageURL, outputPath)
-			}
-
-			if err != nil {
-				log.Printf("Failed to download FFZ emote %s: %v\n", emote.Name, err)
-				continue
-			}
-
-			// Resize if needed
-			if err := resizeImageToMax32(outputPath); err != nil {
-				log.Printf("Failed to resize FFZ emote %s: %v\n", emote.Name, err)
-			}
 
-			log.Printf("Downloaded FFZ emote: %s -> %s\n", emote.Name, outputPath)
+			logDebugf("Downloaded FFZ emote: %s -> %s\n", emote.Name, outputPath)
 
 			channelsFFZ[channelName][emote.Name] = EmoteInfo{
 				ID:       fmt.Sprintf("%d", emote.ID),
 				Name:     emote.Name,
 				ImageURL: imageURL,
 				FilePath: outputPath,
+				Provider: "ffz",
+				Scope:    "channel",
 			}
 		}
 	}
 
-	log.Printf("Processed %d FFZ emotes for channel %s\n", emoteCount, channelName)
+	logDebugf("Processed %d FFZ emotes for channel %s\n", emoteCount, channelName)
 	return nil
 }
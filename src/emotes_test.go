@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakeEmoteAPI answers the provider APIs with canned JSON and every image
+// request with a small PNG
+type fakeEmoteAPI map[string]string
+
+func (f fakeEmoteAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := f[req.URL.String()]
+	if !ok {
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 28, 28)))
+		body = buf.String()
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+// withFakeEmoteAPI points the emote fetchers at api
+func withFakeEmoteAPI(t *testing.T, api fakeEmoteAPI) {
+	t.Helper()
+	oldTransport := http.DefaultTransport
+	http.DefaultTransport = api
+	t.Cleanup(func() { http.DefaultTransport = oldTransport })
+}
+
+func TestFetchEmotesProvider(t *testing.T) {
+	withFakeEmoteAPI(t, fakeEmoteAPI{
+		"https://7tv.io/v3/users/twitch/1": `{"emote_set":{"emotes":[
+			{"id":"s1","name":"provTest7TV","data":{"host":{"url":"//cdn.7tv.app/emote/s1","files":[{"name":"1x.png"}]}}}]}}`,
+		"https://7tv.io/v3/emote-sets/global": `{"emotes":[
+			{"id":"s2","name":"provTest7TVGlobal","data":{"host":{"url":"//cdn.7tv.app/emote/s2","files":[{"name":"1x.png"}]}}}]}`,
+		"https://api.betterttv.net/3/cached/users/twitch/1": `{"channelEmotes":[{"id":"b1","code":"provTestBTTV"}],"sharedEmotes":[]}`,
+		"https://api.betterttv.net/3/cached/emotes/global":  `[{"id":"b2","code":"provTestBTTVGlobal"}]`,
+		"https://api.frankerfacez.com/v1/room/provtest": `{"sets":{"1":{"emoticons":[
+			{"id":1,"name":"provTestFFZ","urls":{"1":"//cdn.frankerfacez.com/emote/1/1"}}]}}}`,
+		"https://api.frankerfacez.com/v1/set/global": `{"sets":{"3":{"emoticons":[
+			{"id":2,"name":"provTestFFZGlobal","urls":{"1":"//cdn.frankerfacez.com/emote/2/1"}}]}}}`,
+	})
+	a := newTestApp(t)
+
+	fetches := map[string]func() error{
+		"7tv channel":  func() error { return a.fetch7TVEmotes("1", "#provtest") },
+		"7tv global":   a.fetch7TVGlobalEmotes,
+		"bttv channel": func() error { return a.fetchBTTVChannelEmotes("1", "#provtest") },
+		"bttv global":  a.fetchBTTVGlobalEmotes,
+		"ffz channel":  func() error { return a.fetchFFZChannelEmotes("1", "#provtest") },
+		"ffz global":   a.fetchFFZGlobalEmotes,
+	}
+	for name, fetch := range fetches {
+		if err := fetch(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		set      emoteSet
+		provider string
+		scope    string
+	}{
+		{"provTest7TV", emoteProviders["7tv"][0], "7tv", "channel"},
+		{"provTest7TVGlobal", emoteProviders["7tv"][1], "7tv", "global"},
+		{"provTestBTTV", emoteProviders["bttv"][0], "bttv", "channel"},
+		{"provTestBTTVGlobal", emoteProviders["bttv"][1], "bttv", "global"},
+		{"provTestFFZ", emoteProviders["ffz"][0], "ffz", "channel"},
+		{"provTestFFZGlobal", emoteProviders["ffz"][1], "ffz", "global"},
+	}
+	for _, tt := range tests {
+		tt.set.mu.RLock()
+		emote, ok := tt.set.get("provtest")[tt.name]
+		tt.set.mu.RUnlock()
+		if !ok {
+			t.Errorf("%s wasn't stored", tt.name)
+			continue
+		}
+		if emote.Provider != tt.provider || emote.Scope != tt.scope {
+			t.Errorf("%s tagged %s/%s, want %s/%s", tt.name, emote.Provider, emote.Scope, tt.provider, tt.scope)
+		}
+	}
+}
+
+func TestParseEmotesProvider(t *testing.T) {
+	a := newTestApp(t)
+
+	channelsBTTVMutex.Lock()
+	channelsBTTV["parsetest"] = map[string]EmoteInfo{
+		"parseTestBTTV": {ID: "b9", Name: "parseTestBTTV", Provider: "bttv", Scope: "channel"},
+	}
+	channelsBTTVMutex.Unlock()
+	t.Cleanup(func() {
+		channelsBTTVMutex.Lock()
+		delete(channelsBTTV, "parsetest")
+		channelsBTTVMutex.Unlock()
+	})
+
+	msg := &Message{
+		Channel: "#parsetest",
+		Content: "Kappa parseTestBTTV",
+		Tags:    map[string]string{"emotes": "25:0-4"},
+	}
+	emotes := a.parseEmotes(msg)
+	if len(emotes) != 2 {
+		t.Fatalf("got %d emotes, want 2", len(emotes))
+	}
+	if emotes[0].Name != "Kappa" || emotes[0].Provider != "twitch" || emotes[0].Scope != "" {
+		t.Errorf("Kappa parsed as %s %s/%s, want twitch with no scope", emotes[0].Name, emotes[0].Provider, emotes[0].Scope)
+	}
+	if emotes[1].Name != "parseTestBTTV" || emotes[1].Provider != "bttv" || emotes[1].Scope != "channel" {
+		t.Errorf("parseTestBTTV parsed as %s %s/%s, want bttv/channel", emotes[1].Name, emotes[1].Provider, emotes[1].Scope)
+	}
+}
+
+func TestFindEmotePriority(t *testing.T) {
+	// the same name from every provider, in both scopes for bttv
+	channelsMutex.Lock()
+	channels["prioritytest"] = Channel{Name: "prioritytest", Emotes: map[string]EmoteInfo{
+		"Clash": {Name: "Clash", Provider: "7tv", Scope: "channel"},
+	}}
+	channelsMutex.Unlock()
+	channelsBTTVMutex.Lock()
+	channelsBTTV["prioritytest"] = map[string]EmoteInfo{
+		"Clash": {Name: "Clash", Provider: "bttv", Scope: "channel"},
+	}
+	channelsBTTVMutex.Unlock()
+	globalBTTVMutex.Lock()
+	globalBTTVEmotes["Clash"] = EmoteInfo{Name: "Clash", Provider: "bttv", Scope: "global"}
+	globalBTTVMutex.Unlock()
+	globalFFZMutex.Lock()
+	globalFFZEmotes["Clash"] = EmoteInfo{Name: "Clash", Provider: "ffz", Scope: "global"}
+	globalFFZMutex.Unlock()
+
+	t.Cleanup(func() {
+		channelsMutex.Lock()
+		delete(channels, "prioritytest")
+		channelsMutex.Unlock()
+		channelsBTTVMutex.Lock()
+		delete(channelsBTTV, "prioritytest")
+		channelsBTTVMutex.Unlock()
+		globalBTTVMutex.Lock()
+		delete(globalBTTVEmotes, "Clash")
+		globalBTTVMutex.Unlock()
+		globalFFZMutex.Lock()
+		delete(globalFFZEmotes, "Clash")
+		globalFFZMutex.Unlock()
+	})
+
+	tests := []struct {
+		priority     string
+		wantProvider string
+		wantScope    string
+	}{
+		{"7tv,bttv,ffz", "7tv", "channel"},
+		{"bttv,7tv,ffz", "bttv", "channel"},
+		{"ffz", "ffz", "global"},
+		{"ffz,bttv", "ffz", "global"},
+	}
+	for _, tt := range tests {
+		priority, err := parseEmotePriority(tt.priority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := newTestApp(t)
+		a.config.EmotePriority = priority
+
+		emote, ok := a.findEmote("#prioritytest", "Clash")
+		if !ok {
+			t.Fatalf("%s: Clash not found", tt.priority)
+		}
+		if emote.Provider != tt.wantProvider || emote.Scope != tt.wantScope {
+			t.Errorf("%s: Clash resolved to %s/%s, want %s/%s", tt.priority, emote.Provider, emote.Scope, tt.wantProvider, tt.wantScope)
+		}
+		// the picker offers the image chat renders
+		results := a.SearchEmotes("#prioritytest", "Clash", 1)
+		if len(results) != 1 || results[0].Provider != tt.wantProvider || results[0].Scope != tt.wantScope {
+			t.Errorf("%s: SearchEmotes gave %+v, want %s/%s", tt.priority, results, tt.wantProvider, tt.wantScope)
+		}
+	}
+
+	// with the channel bttv Clash gone, global bttv still beats channel 7tv
+	// because bttv is listed first
+	a := newTestApp(t)
+	a.config.EmotePriority = []string{"bttv", "7tv", "ffz"}
+	channelsBTTVMutex.Lock()
+	delete(channelsBTTV, "prioritytest")
+	channelsBTTVMutex.Unlock()
+	if emote, _ := a.findEmote("#prioritytest", "Clash"); emote.Provider != "bttv" || emote.Scope != "global" {
+		t.Errorf("without a channel bttv emote Clash resolved to %s/%s, want bttv/global", emote.Provider, emote.Scope)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error kinds shared by the IRC client and the HTTP callers (GQL, emote
+// APIs), so callers can tell a network problem from a bad response with
+// errors.Is/errors.As instead of matching strings.
+var (
+	ErrConnect = errors.New("unreachable")  // dial or request failed, no response
+	ErrDecode  = errors.New("bad response") // got a response we couldn't parse
+)
+
+// APIStatusError is an HTTP response with an unexpected status code
+type APIStatusError struct {
+	API  string
+	Code int
+}
+
+func (e *APIStatusError) Error() string {
+	return fmt.Sprintf("%s: bad status %d", e.API, e.Code)
+}
+
+// Temporary reports whether the same request might work later
+func (e *APIStatusError) Temporary() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
+
+// retryable reports whether err is worth retrying: network failures and
+// rate limit/server errors are, bad requests and garbage responses aren't.
+func retryable(err error) bool {
+	if errors.Is(err, ErrConnect) {
+		return true
+	}
+	var statusErr *APIStatusError
+	return errors.As(err, &statusErr) && statusErr.Temporary()
+}
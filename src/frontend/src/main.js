@@ -37,6 +37,8 @@ document.addEventListener("DOMContentLoaded", async () => {
     await loadBufferSize();
     setupWailsEventListeners();
     updateButtonVisibility();
+    // listeners are in place, backend can start connecting
+    runtime.EventsEmit("frontend-ready");
 });
 
 // Load buffer size from backend
@@ -310,6 +312,14 @@ function setupWailsEventListeners() {
         addMessageToChat(message);
     });
 
+    // $collapse folded a repeat into an earlier message, bump its count
+    runtime.EventsOn("message-collapsed", (data) => {
+        const messageEl = messageElements.find((el) => el.dataset.id === data.id);
+        if (messageEl) {
+            setRepeatCount(messageEl, data.repeatCount);
+        }
+    });
+
     runtime.EventsOn("highlight-channel", (message) => {
         highlightChannel(message.channel);
     });
@@ -406,6 +416,10 @@ function setupWailsEventListeners() {
         }
     });
 
+    runtime.EventsOn("desktop-notification", (data) => {
+        showDesktopNotification(data.title, data.body);
+    });
+
     // Listen for channel live status updates
     runtime.EventsOn("channel-live-status", (data) => {
         console.log("Received live status update:", data);
@@ -414,6 +428,21 @@ function setupWailsEventListeners() {
     });
 }
 
+// OS notification, only when the window isn't focused
+function showDesktopNotification(title, body) {
+    if (!("Notification" in window) || document.hasFocus()) return;
+
+    if (Notification.permission === "granted") {
+        new Notification(title, { body });
+    } else if (Notification.permission !== "denied") {
+        Notification.requestPermission().then((permission) => {
+            if (permission === "granted") {
+                new Notification(title, { body });
+            }
+        });
+    }
+}
+
 // Switch to channel (connect if needed)
 async function switchToChannel(channel) {
     if (!channel) return;
@@ -681,9 +710,28 @@ function sortChannelList() {
     items.forEach((item) => container.appendChild(item));
 }
 
-// Helper function to escape regex special characters
-function escapeRegExp(string) {
-    return string.replace(/[.*+?^${}()|[\]\\]/g, "\\$&");
+// Escapes text and swaps emote names for their images. Emotes are whole
+// space-separated words, so the text is split into words first: names like
+// ":)" or "<3" match, and nothing is replaced inside the markup built here.
+function renderMessageContent(message, text) {
+    const emotes = message.emotes || {};
+    const missing = new Set(message.missingEmotes || []);
+
+    return text
+        .split(/(\s+)/)
+        .map((word) => {
+            const escaped = escapeHtml(word);
+            const attr = escaped.replace(/"/g, "&quot;");
+            if (Object.hasOwn(emotes, word)) {
+                return `<img src="${emotes[word]}" alt="${attr}" class="emote" title="${attr}"/>`;
+            }
+            // No image for these (still downloading or failed), keep them as text
+            if (missing.has(word)) {
+                return `<span class="emote-missing" title="${attr} (image unavailable)">${escaped}</span>`;
+            }
+            return escaped;
+        })
+        .join("");
 }
 
 // Add a message to the chat with ring buffer functionality
@@ -692,9 +740,11 @@ function addMessageToChat(message, shouldScroll = true) {
 
     const messageEl = document.createElement("div");
     messageEl.className = "chat-message";
+    if (message.id) {
+        messageEl.dataset.id = message.id;
+    }
 
     const usernameColor = message.userColor || "#ffffff";
-    let contentHtml = escapeHtml(message.content);
 
     if (message.isHighlighted) {
         messageEl.classList.add("message-highlighted");
@@ -704,25 +754,42 @@ function addMessageToChat(message, shouldScroll = true) {
         messageEl.classList.add("message-highlighted");
     }
 
-    if (message.emotes) {
-        for (const [emoteName, base64] of Object.entries(message.emotes)) {
-            const escapedName = escapeRegExp(emoteName);
-            const regex = new RegExp(`\\b${escapedName}\\b`, "g");
-            contentHtml = contentHtml.replace(
-                regex,
-                `<img src="${base64}" 
-                      alt="${emoteName}" 
-                      class="emote" 
-                      title="${emoteName}"/>`
-            );
-        }
+    // Long messages ($truncate) show a preview until clicked
+    let contentHtml = renderMessageContent(message, message.truncated ? message.preview : message.content);
+    if (message.truncated) {
+        contentHtml += ` <a href="#" class="show-more">… show more</a>`;
+    }
+
+    // Sent in a partner channel of a shared chat session
+    let sourceHtml = "";
+    if (message.sourceRoomId) {
+        const source = message.sourceChannel || "shared chat";
+        sourceHtml = `<span class="shared-source" title="Sent in ${escapeHtml(source)}">${escapeHtml(source)}</span>`;
     }
 
     messageEl.innerHTML = `
         <span class="timestamp">[${message.timestamp}]</span>
+        ${sourceHtml}
         <span class="username" style="color: ${usernameColor}">${message.username}:</span>
         <span class="message-content">${contentHtml}</span>
     `;
+    if (message.repeatCount > 1) {
+        setRepeatCount(messageEl, message.repeatCount);
+    }
+
+    if (message.truncated) {
+        messageEl.querySelector(".show-more").addEventListener("click", (e) => {
+            e.preventDefault();
+            messageEl.querySelector(".message-content").innerHTML = renderMessageContent(message, message.content);
+        });
+    }
+
+    // /me messages are shown in the user's color and italicized, like on twitch
+    if (message.isAction) {
+        const contentEl = messageEl.querySelector(".message-content");
+        contentEl.style.color = usernameColor;
+        contentEl.style.fontStyle = "italic";
+    }
 
     chatMessages.appendChild(messageEl);
     messageElements.push(messageEl);
@@ -740,6 +807,17 @@ function addMessageToChat(message, shouldScroll = true) {
     }
 }
 
+// setRepeatCount shows how often a collapsed message was repeated ("x42")
+function setRepeatCount(messageEl, count) {
+    let countEl = messageEl.querySelector(".repeat-count");
+    if (!countEl) {
+        countEl = document.createElement("span");
+        countEl.className = "repeat-count";
+        messageEl.appendChild(countEl);
+    }
+    countEl.textContent = `x${count}`;
+}
+
 // Add a reward redemption to chat
 function addRewardToChat(reward) {
     if (!chatMessages) return;
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// At most this many highlight actions per minute, a flood of matches
+// shouldn't spawn unbounded processes or requests. Extra ones are dropped.
+const highlightActionsPerMinute = 10
+
+// dingAllowed reports whether a highlight at now may play the ding. The
+// message is highlighted either way, only the sound is held back.
+func (a *App) dingAllowed(now time.Time) bool {
+	if a.config.HighlightCooldown <= 0 {
+		return true
+	}
+	for {
+		last := a.lastDing.Load()
+		if last != 0 && now.Sub(time.Unix(0, last)) < a.config.HighlightCooldown {
+			return false
+		}
+		if a.lastDing.CompareAndSwap(last, now.UnixNano()) {
+			return true
+		}
+	}
+}
+
+var highlightHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// runHighlightActions fires the configured $highlightwebhook and
+// $highlightexec for a highlighted message. Failures are only logged.
+func (a *App) runHighlightActions(msg Message) {
+	webhook, command := a.config.HighlightWebhook, a.config.HighlightExec
+	if webhook == "" && command == "" {
+		return
+	}
+	if !a.highlightLimiter.Allow() {
+		logWarnf("Highlight action rate limit hit, skipping for %s in %s", msg.Username, msg.Channel)
+		return
+	}
+
+	channel := strings.TrimPrefix(msg.Channel, "#")
+	if webhook != "" {
+		go func() {
+			if err := postHighlightWebhook(webhook, channel, msg); err != nil {
+				logWarnf("Highlight webhook failed: %v", err)
+			}
+		}()
+	}
+	if command != "" {
+		go func() {
+			if err := runHighlightExec(command, channel, msg); err != nil {
+				logWarnf("Highlight command failed: %v", err)
+			}
+		}()
+	}
+}
+
+func postHighlightWebhook(url, channel string, msg Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"channel":   channel,
+		"username":  msg.Username,
+		"content":   msg.Content,
+		"timestamp": msg.Timestamp.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := highlightHTTPClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnect, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &APIStatusError{API: "webhook", Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// runHighlightExec runs the command with the message details in env vars
+// rather than arguments, so chat text never ends up parsed by a shell
+func runHighlightExec(command, channel string, msg Message) error {
+	fields := splitCommand(command)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+	cmd.Env = append(os.Environ(),
+		"WATCHERINO_CHANNEL="+channel,
+		"WATCHERINO_USERNAME="+msg.Username,
+		"WATCHERINO_MESSAGE="+msg.Content,
+	)
+
+	if runtime.GOOS == "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	}
+
+	return cmd.Run()
+}
+
+// splitCommand splits a command line on whitespace, with double quotes
+// grouping a field so paths with spaces work:
+// "C:\Program Files\foo.exe" --bar. Backslashes are kept as-is.
+func splitCommand(command string) []string {
+	var fields []string
+	var field strings.Builder
+	inField, quoted := false, false
+	for _, r := range command {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
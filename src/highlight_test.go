@@ -0,0 +1,24 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"", nil},
+		{"  notify-send  hi ", []string{"notify-send", "hi"}},
+		{`"C:\Program Files\foo\foo.exe" --bar`, []string{`C:\Program Files\foo\foo.exe`, "--bar"}},
+		{`run --title="new highlight" x`, []string{"run", "--title=new highlight", "x"}},
+		{`run ""`, []string{"run", ""}},
+	}
+	for _, tt := range tests {
+		if got := splitCommand(tt.command); !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// loadIgnoredUsers merges $ignore with the users ignored at runtime
+func (a *App) loadIgnoredUsers() map[string]bool {
+	ignored := make(map[string]bool)
+	for login := range a.config.IgnoredUsers {
+		ignored[login] = true
+	}
+	for _, login := range a.getPreferences().IgnoredUsers {
+		ignored[login] = true
+	}
+	return ignored
+}
+
+func (a *App) isIgnored(login string) bool {
+	if login == "" {
+		return false
+	}
+	a.ignoredMu.RLock()
+	defer a.ignoredMu.RUnlock()
+	return a.ignored[login]
+}
+
+// IgnoreUser hides messages from login in every channel, remembered across
+// restarts. Matches the account name, not the display name.
+func (a *App) IgnoreUser(name string) error {
+	login, err := normalizeChannelName(name) // same rules as channel names
+	if err != nil {
+		return fmt.Errorf("invalid login %q", name)
+	}
+
+	a.ignoredMu.Lock()
+	a.ignored[login] = true
+	a.ignoredMu.Unlock()
+
+	a.updatePreferences(func(p *Preferences) {
+		if !slices.Contains(p.IgnoredUsers, login) {
+			p.IgnoredUsers = append(slices.Clone(p.IgnoredUsers), login)
+		}
+	})
+	return nil
+}
+
+// UnignoreUser shows login's messages again. Users listed in $ignore have to
+// be removed there.
+func (a *App) UnignoreUser(login string) error {
+	login = strings.ToLower(strings.TrimSpace(login))
+	if a.config.IgnoredUsers[login] {
+		return fmt.Errorf("%s is in $ignore in %s, remove it there", login, a.configPath)
+	}
+
+	a.ignoredMu.Lock()
+	delete(a.ignored, login)
+	a.ignoredMu.Unlock()
+
+	a.updatePreferences(func(p *Preferences) {
+		kept := make([]string, 0, len(p.IgnoredUsers))
+		for _, l := range p.IgnoredUsers {
+			if l != login {
+				kept = append(kept, l)
+			}
+		}
+		p.IgnoredUsers = kept
+	})
+	return nil
+}
+
+// GetIgnoredUsers returns the ignored logins, sorted
+func (a *App) GetIgnoredUsers() []string {
+	a.ignoredMu.RLock()
+	defer a.ignoredMu.RUnlock()
+	logins := make([]string, 0, len(a.ignored))
+	for login := range a.ignored {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	return logins
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ircMux carries every channel over one IRC connection ($multiplex) instead
+// of a socket per channel. Channels keep their own Client for parsing,
+// buffers and events; the mux owns the socket, answers PINGs, routes lines
+// by #channel and re-JOINs everything after a reconnect.
+type ircMux struct {
+	mu      sync.RWMutex
+	conn    net.Conn
+	nick    string
+	token   string
+	retries int                  // nicks replaced after a 433
+	clients map[string][]*Client // "#channel" -> clients, newest last
+
+	config      *TwitchConfig
+	joinLimiter *RateLimiter
+
+	lastRead atomic.Int64 // unix nanos of the last line from the server
+}
+
+func newIRCMux(config *TwitchConfig, joinLimiter *RateLimiter) *ircMux {
+	return &ircMux{
+		clients:     make(map[string][]*Client),
+		config:      config,
+		joinLimiter: joinLimiter,
+	}
+}
+
+// join adds c to the shared connection, dialing it first if needed. The
+// first client's credentials are used for the connection.
+func (m *ircMux) join(c *Client) error {
+	for {
+		m.mu.RLock()
+		connected := m.conn != nil
+		m.mu.RUnlock()
+
+		// dialed without the lock, a slow dial mustn't stall routing and
+		// parting on the other channels
+		var dialed net.Conn
+		if !connected {
+			var err error
+			if dialed, err = dialIRC(m.config); err != nil {
+				return err
+			}
+		}
+
+		m.mu.Lock()
+		if m.conn == nil && dialed != nil {
+			m.nick, m.token = c.credentials()
+			registerIRC(dialed, m.nick, m.token, m.config.Membership, c.sendRaw)
+			m.conn = dialed
+			go m.listen(dialed)
+		} else if dialed != nil {
+			// a racing join connected first
+			dialed.Close()
+		}
+		if m.conn != nil {
+			break
+		}
+		// the last channel parted and closed it since we looked
+		m.mu.Unlock()
+	}
+	conn := m.conn
+	// a racing ConnectToChannel can join the channel a second time, both
+	// stay registered until they part
+	if !slices.Contains(m.clients[c.channel], c) {
+		m.clients[c.channel] = append(m.clients[c.channel], c)
+	}
+	m.mu.Unlock()
+
+	m.joinLimiter.Wait()
+	if _, err := fmt.Fprintf(conn, "JOIN %s\r\n", c.channel); err != nil {
+		// listen notices the dead socket and re-JOINs everyone
+		logWarnf("JOIN %s failed on the shared connection: %v", c.channel, err)
+	}
+	c.sendRaw("JOIN "+c.channel, true)
+	c.attach(conn)
+	return nil
+}
+
+// part removes c, PARTing its channel once no other client is in it and
+// closing the connection once no channel is left on it
+func (m *ircMux) part(c *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.Index(m.clients[c.channel], c)
+	if i < 0 {
+		return
+	}
+	remaining := slices.Delete(slices.Clone(m.clients[c.channel]), i, i+1)
+	if len(remaining) > 0 {
+		m.clients[c.channel] = remaining
+		return
+	}
+	delete(m.clients, c.channel)
+	if m.conn == nil {
+		return
+	}
+	if len(m.clients) == 0 {
+		m.conn.Close()
+		m.conn = nil
+		return
+	}
+	fmt.Fprintf(m.conn, "PART %s\r\n", c.channel)
+}
+
+// client returns the newest client in channel
+func (m *ircMux) client(channel string) *Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if clients := m.clients[channel]; len(clients) > 0 {
+		return clients[len(clients)-1]
+	}
+	return nil
+}
+
+// retryNick is Client.retryNick for the shared connection, every channel
+// is joined again
+func (m *ircMux) retryNick(conn net.Conn) {
+	m.mu.Lock()
+	if m.token != "" || m.retries >= maxNickRetries {
+		nick := m.nick
+		m.mu.Unlock()
+		logWarnf("Nick %s rejected on the shared connection", nick)
+		return
+	}
+	m.retries++
+	m.nick = anonymousNick()
+	nick := m.nick
+	channels := make([]string, 0, len(m.clients))
+	for channel := range m.clients {
+		channels = append(channels, channel)
+	}
+	m.mu.Unlock()
+
+	logWarnf("Nick rejected on the shared connection, retrying as %s", nick)
+	fmt.Fprintf(conn, "NICK %s\r\n", nick)
+	go func() {
+		for _, channel := range channels {
+			m.joinLimiter.Wait()
+			fmt.Fprintf(conn, "JOIN %s\r\n", channel)
+		}
+	}()
+}
+
+func (m *ircMux) anyChannel() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for channel := range m.clients {
+		return channel
+	}
+	return ""
+}
+
+func (m *ircMux) listen(conn net.Conn) {
+	for {
+		done := make(chan struct{})
+		go m.keepalive(conn, done)
+
+		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxIRCLineSize)
+		scanner.Split(scanIRCLines)
+		conn.SetReadDeadline(time.Now().Add(readIdleTimeout))
+		serverReconnect := false
+		for scanner.Scan() {
+			conn.SetReadDeadline(time.Now().Add(readIdleTimeout))
+			m.lastRead.Store(time.Now().UnixNano())
+			data := scanner.Text()
+			if data == "" {
+				continue
+			}
+			if ircCommand(data) == "PING" {
+				fmt.Fprint(conn, pongFor(data))
+				continue
+			}
+			if ircCommand(data) == "433" {
+				m.retryNick(conn)
+				continue
+			}
+			if isReconnect(data) {
+				logInfof("Twitch asked the shared connection to reconnect")
+				serverReconnect = true
+				break
+			}
+			channel := lineChannel(data)
+			if command := ircCommand(data); channel == "" && (command == "WHISPER" || command == "GLOBALUSERSTATE") {
+				// not about a channel, any client will do
+				channel = m.anyChannel()
+			}
+			if c := m.client(channel); c != nil {
+				c.sendRaw(data, false)
+				c.handleLine(data)
+			} else {
+				// welcome and CAP replies, or a channel parted meanwhile
+				logDebugf("Unrouted line on the shared connection: %s", redactIRC(data))
+			}
+		}
+		close(done)
+
+		if err := scanner.Err(); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				logWarnf("Nothing on the shared connection in %v, assuming it's dead", readIdleTimeout)
+			} else {
+				logWarnf("Read error on the shared connection: %v", err)
+			}
+		}
+		conn.Close()
+
+		if conn = m.reconnect(conn, serverReconnect); conn == nil {
+			return
+		}
+	}
+}
+
+// reconnect redials after old dropped and re-JOINs every channel, right
+// away the first time if the server asked for it. It returns nil when
+// there's nothing left to reconnect, i.e. all channels parted or a newer
+// connection already took over.
+func (m *ircMux) reconnect(old net.Conn, immediate bool) net.Conn {
+	m.mu.RLock()
+	for _, clients := range m.clients {
+		for _, c := range clients {
+			c.mu.Lock()
+			c.connected = false
+			c.mu.Unlock()
+		}
+	}
+	m.mu.RUnlock()
+
+	for {
+		m.mu.Lock()
+		if m.conn != old || len(m.clients) == 0 {
+			if m.conn == old {
+				m.conn = nil
+			}
+			m.mu.Unlock()
+			return nil
+		}
+		m.mu.Unlock()
+
+		if immediate {
+			immediate = false
+		} else {
+			logWarnf("Shared connection lost, reconnecting...")
+			time.Sleep(5 * time.Second)
+		}
+		conn, err := dialIRC(m.config)
+		if err != nil {
+			logWarnf("Reconnect failed: %v", err)
+			continue
+		}
+
+		m.mu.Lock()
+		if m.conn != old {
+			m.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		registerIRC(conn, m.nick, m.token, m.config.Membership, func(string, bool) {})
+		m.conn = conn
+		clients := make(map[string][]*Client, len(m.clients))
+		for channel, c := range m.clients {
+			clients[channel] = slices.Clone(c)
+		}
+		m.mu.Unlock()
+
+		// re-JOIN in the background, reading has to go on meanwhile
+		go func() {
+			for channel, channelClients := range clients {
+				m.joinLimiter.Wait()
+				fmt.Fprintf(conn, "JOIN %s\r\n", channel)
+				for _, c := range channelClients {
+					c.attach(conn)
+				}
+			}
+			logInfof("Rejoined %d channels on the shared connection", len(clients))
+		}()
+		return conn
+	}
+}
+
+// keepalive is Client.keepalive for the shared connection
+func (m *ircMux) keepalive(conn net.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			fmt.Fprint(conn, "PING :tmi.twitch.tv\r\n")
+		}
+	}
+}
+
+// lineChannel returns the #channel a server line is about, "" for lines
+// that aren't about one (CAP, welcome, GLOBALUSERSTATE)
+func lineChannel(data string) string {
+	if strings.HasPrefix(data, "@") {
+		i := strings.IndexByte(data, ' ')
+		if i < 0 {
+			return ""
+		}
+		data = data[i+1:]
+	}
+	data = strings.TrimPrefix(data, ":")
+	// the trailing parameter is free text, e.g. the chat message
+	if i := strings.Index(data, " :"); i >= 0 {
+		data = data[:i]
+	}
+	for _, field := range strings.Fields(data) {
+		if strings.HasPrefix(field, "#") {
+			return strings.ToLower(field)
+		}
+	}
+	return ""
+}
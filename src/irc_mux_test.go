@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMuxPartRacingClient(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+	m := &ircMux{conn: conn, clients: make(map[string][]*Client)}
+
+	first, second, other := newTestClient("#chan"), newTestClient("#chan"), newTestClient("#other")
+	m.clients["#chan"] = []*Client{first, second}
+	m.clients["#other"] = []*Client{other}
+
+	parted := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(server).ReadString('\n')
+		parted <- line
+	}()
+
+	// the losing ConnectToChannel stops its client, the channel stays joined
+	m.part(second)
+	if got := m.client("#chan"); got != first {
+		t.Fatal("stopping the second client unregistered the first")
+	}
+	select {
+	case line := <-parted:
+		t.Fatalf("sent %q while a client was still in the channel", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	m.part(first)
+	select {
+	case line := <-parted:
+		if line != "PART #chan\r\n" {
+			t.Errorf("sent %q, want PART #chan", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no PART once the last client left")
+	}
+	if m.client("#chan") != nil {
+		t.Error("#chan still has a client")
+	}
+}
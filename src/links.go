@@ -0,0 +1,43 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// URLs plus the scheme-less invite forms people paste, e.g. discord.gg/abc
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b(?:discord\.gg|discord(?:app)?\.com/invite|twitch\.tv|t\.me)/\S+`)
+
+const linkMask = "[link]"
+
+// hideLinks applies $hidelinks to displayed content: "mask" replaces links
+// with [link], "strip" drops them. Logs keep the original text.
+func hideLinks(content, mode string) string {
+	switch mode {
+	case "mask":
+		return linkPattern.ReplaceAllString(content, linkMask)
+	case "strip":
+		stripped := linkPattern.ReplaceAllString(content, "")
+		return strings.Join(strings.Fields(stripped), " ")
+	}
+	return content
+}
+
+// truncatePreview cuts displayed content longer than n runes ($truncate)
+// back to the last space, so an emote or link is never split. ok is false
+// when the content fits.
+func truncatePreview(content string, n int) (preview string, ok bool) {
+	runes := []rune(content)
+	if n <= 0 || len(runes) <= n {
+		return content, false
+	}
+	cut := n
+	for i := n; i > n/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace), true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+)
+
+// LiveSession is one stream, End is nil while it's still going
+type LiveSession struct {
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end,omitempty"`
+	Duration int        `json:"duration"` // seconds, up to now for ongoing ones
+}
+
+// Sessions kept per channel in channels/<login>/live_history.json
+const maxLiveSessions = 50
+
+func (a *App) liveHistoryPath(login string) string {
+	return a.dataPath("channels", login, "live_history.json")
+}
+
+func (a *App) loadLiveHistory(login string) []LiveSession {
+	data, err := os.ReadFile(a.liveHistoryPath(login))
+	if err != nil {
+		return nil
+	}
+	var sessions []LiveSession
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		logWarnf("Ignoring unreadable live history for %s: %v", login, err)
+		return nil
+	}
+	return sessions
+}
+
+// recordLiveStatus notes a live status change, including the first status
+// seen after startup. Times are when we noticed, so up to one poll late. A
+// stream still open from before a restart is carried on, or closed now if
+// the channel went offline meanwhile.
+func (a *App) recordLiveStatus(login string, live bool) {
+	a.liveHistoryMu.Lock()
+	defer a.liveHistoryMu.Unlock()
+
+	sessions := a.loadLiveHistory(login)
+	open := len(sessions) > 0 && sessions[len(sessions)-1].End == nil
+	now := time.Now()
+	switch {
+	case live && !open:
+		sessions = append(sessions, LiveSession{Start: now})
+	case !live && open:
+		sessions[len(sessions)-1].End = &now
+		sessions[len(sessions)-1].Duration = int(now.Sub(sessions[len(sessions)-1].Start).Seconds())
+	default:
+		return
+	}
+	if len(sessions) > maxLiveSessions {
+		sessions = sessions[len(sessions)-maxLiveSessions:]
+	}
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(a.dataPath("channels", login), 0755); err != nil {
+		logWarnf("Couldn't save live history for %s: %v", login, err)
+		return
+	}
+	if err := writeFileAtomic(a.liveHistoryPath(login), data, 0644); err != nil {
+		logWarnf("Couldn't save live history for %s: %v", login, err)
+	}
+}
+
+// GetLiveHistory returns a channel's recent streams, newest first
+func (a *App) GetLiveHistory(channel string) ([]LiveSession, error) {
+	login, err := normalizeChannelName(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	a.liveHistoryMu.Lock()
+	sessions := a.loadLiveHistory(login)
+	a.liveHistoryMu.Unlock()
+
+	for i := range sessions {
+		if sessions[i].End == nil {
+			sessions[i].Duration = int(time.Since(sessions[i].Start).Seconds())
+		}
+	}
+	slices.Reverse(sessions)
+	if sessions == nil {
+		sessions = []LiveSession{}
+	}
+	return sessions, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRecordLiveStatusStartup(t *testing.T) {
+	a := newTestApp(t)
+
+	// already live at launch: a session is opened and closed later
+	a.recordLiveStatus("livenow", true)
+	sessions := a.loadLiveHistory("livenow")
+	if len(sessions) != 1 || sessions[0].End != nil {
+		t.Fatalf("after startup live: %+v, want one open session", sessions)
+	}
+	a.recordLiveStatus("livenow", false)
+	sessions = a.loadLiveHistory("livenow")
+	if len(sessions) != 1 || sessions[0].End == nil {
+		t.Fatalf("after going offline: %+v, want one closed session", sessions)
+	}
+
+	// left open by a crash, offline at launch: closed now
+	start := time.Now().Add(-2 * time.Hour)
+	data, _ := json.Marshal([]LiveSession{{Start: start}})
+	if err := os.MkdirAll(a.dataPath("channels", "crashed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a.liveHistoryPath("crashed"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.recordLiveStatus("crashed", false)
+	sessions = a.loadLiveHistory("crashed")
+	if len(sessions) != 1 || sessions[0].End == nil {
+		t.Fatalf("stale session: %+v, want it closed", sessions)
+	}
+	if sessions[0].Duration < int((2 * time.Hour).Seconds()) {
+		t.Errorf("duration = %ds, want at least 2h", sessions[0].Duration)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Log levels for $loglevel. Info is the default and the zero value.
+type logLevel int32
+
+const (
+	levelDebug logLevel = iota - 1
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var currentLogLevel atomic.Int32
+
+func parseLogLevel(s string) (logLevel, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return levelDebug, nil
+	case "info", "":
+		return levelInfo, nil
+	case "warn", "warning":
+		return levelWarn, nil
+	case "error":
+		return levelError, nil
+	}
+	return levelInfo, fmt.Errorf("expected debug, info, warn or error")
+}
+
+func logAt(level logLevel, prefix, format string, args ...interface{}) {
+	if level < logLevel(currentLogLevel.Load()) {
+		return
+	}
+	log.Output(3, prefix+fmt.Sprintf(format, args...))
+}
+
+// logDebugf is for step-by-step detail: connection steps, every emote
+// download, every status poll
+func logDebugf(format string, args ...interface{}) { logAt(levelDebug, "DEBUG ", format, args...) }
+
+func logInfof(format string, args ...interface{}) { logAt(levelInfo, "", format, args...) }
+
+// logWarnf is for failures the app works around, e.g. a failed download
+func logWarnf(format string, args ...interface{}) { logAt(levelWarn, "WARN ", format, args...) }
+
+// logErrorf is for failures that lose something, e.g. a log file that
+// can't be written
+func logErrorf(format string, args ...interface{}) { logAt(levelError, "ERROR ", format, args...) }
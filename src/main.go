@@ -3,7 +3,9 @@ package main
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -18,25 +20,6 @@ import (
 //go:embed all:frontend
 var assets embed.FS
 
-var bufferSize int = 256
-var otoCtx, _ = initOto()
-var loggerList map[string]*os.File = make(map[string]*os.File)
-
-var filterList = GetTwitchConfigFromFile("config.txt").FilterList
-
-var toRecord = GetTwitchConfigFromFile("config.txt").RecordingEnabled
-
-var channels_map = GetChannelsFromConfig("config.txt")
-
-var archiveDir = GetTwitchConfigFromFile("config.txt").ArchiveDir
-
-var streamlinkPids = make([]int, 0)
-
-var audioMuted = false
-var audioLocked = false
-
-var audioRecorder = NewTwitchRecorder("none", "none")
-
 func containsAny(text string, keywords []string) bool {
 	textLower := strings.ToLower(text)
 	for _, keyword := range keywords {
@@ -47,61 +30,64 @@ func containsAny(text string, keywords []string) bool {
 	return false
 }
 
-func cleanupStreamlinkProcs() {
-	for _, pid := range streamlinkPids {
-		p, err := os.FindProcess(pid)
-		if err == nil {
-			_ = p.Kill()
-			log.Printf("Killed streamlink process: %d", pid)
-		}
-	}
-}
-
 func main() {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Panic recovered: %v", r)
+			logErrorf("Panic recovered: %v", r)
 		}
-		cleanupStreamlinkProcs()
-		audioRecorder.StopAudio()
 	}()
 
-	os.Mkdir("logs", 0700)
-	log.Println(filterList)
+	// config.txt is read once at startup. Problems don't stop the app, they're
+	// kept in configErr and shown to the user once the window is up.
+	configPath := resolveConfigPath()
+	config, configErr := LoadTwitchConfig(configPath)
+	channels, channelsErr := LoadChannelsFromConfig(configPath)
+	configErr = errors.Join(configErr, channelsErr)
+	currentLogLevel.Store(int32(config.LogLevel))
+
+	// Everything the app writes goes under $datadir, the working directory
+	// unless set. Recordings too, unless $archivedir says otherwise.
+	os.MkdirAll(filepath.Join(config.DataDir, "logs"), 0700)
+	logInfof("Using config %s", configPath)
+	logDebugf("Filter: %v", config.FilterList)
+	if configErr != nil {
+		logWarnf("Config problems: %v", configErr)
+	}
 
 	t := time.Now()
 	formatted := fmt.Sprintf("%d-%02d-%02d",
 		t.Year(), t.Month(), t.Day())
 
-	f, err := os.OpenFile(filepath.Join("logs", formatted+"_log.txt"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	f, err := os.OpenFile(filepath.Join(config.DataDir, "logs", formatted+"_log.txt"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		log.Fatalf("error opening file: %v", err)
 	}
 	defer f.Close()
 
-	generateTTSFiles()
+	app := NewApp(configPath, config, channels, configErr)
+	defer app.cleanupStreamlinkProcs()
+	defer app.audio.StopAudio()
+
+	if !app.authConfigured() {
+		logInfof("No $nick/$oauth, reading chat anonymously. Off: %s",
+			strings.Join(app.authDisabledFeatures(), ", "))
+	}
+
+	// opened here rather than in NewApp, tests build Apps without a sound card
+	app.audioOut, app.audioOutErr = initOto()
+	app.initTTS()
 
-	// Check if we're running with a console
-	// if isConsoleAvailable() {
-	// 	mw := io.MultiWriter(os.Stdout, f)
-	// 	log.SetOutput(mw)
-	// } else {
-	// 	log.SetOutput(f)
-	// }
+	// -console or $console=true also logs to a console, for debugging
+	// packaged builds
 	log.SetOutput(f)
-	go func() {
-		if err := Fetch7TVGlobalEmotes(); err != nil {
-			log.Printf("failed to fetch 7TV global emotes: %v", err)
+	if config.Console || hasFlag("console") {
+		if out, err := openConsole(); err != nil {
+			logWarnf("Couldn't open a console: %v", err)
+		} else {
+			log.SetOutput(io.MultiWriter(out, f))
 		}
-		if err := FetchBTTVGlobalEmotes(); err != nil {
-			log.Printf("failed to fetch BTTV global emotes: %v", err)
-		}
-		if err := FetchFFZGlobalEmotes(); err != nil {
-			log.Printf("failed to fetch FFZ global emotes: %v", err)
-		}
-	}()
-
-	app := NewApp()
+	}
+	go app.fetchGlobalEmotes()
 
 	err = wails.Run(&options.App{
 		Title:  "Twitch Chat",
@@ -112,6 +98,7 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 26, G: 26, B: 26, A: 1},
 		OnStartup:        app.OnStartup,
+		OnBeforeClose:    app.OnBeforeClose,
 		Bind: []interface{}{
 			app,
 		},
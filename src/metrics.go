@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Counters since startup, for GetMetrics. Per-channel message counts are
+// keyed like the connections map, with the "#".
+type appMetrics struct {
+	mu       sync.Mutex
+	messages map[string]int64
+
+	emotesParsed    atomic.Int64
+	downloads       atomic.Int64
+	downloadsOK     atomic.Int64
+	downloadsFailed atomic.Int64
+	apiCalls        atomic.Int64
+}
+
+func (m *appMetrics) countMessage(channel string) {
+	m.mu.Lock()
+	if m.messages == nil {
+		m.messages = make(map[string]int64)
+	}
+	m.messages[channel]++
+	m.mu.Unlock()
+}
+
+// countDownload records the outcome of one emote download
+func (m *appMetrics) countDownload(err error) {
+	m.downloads.Add(1)
+	if err != nil {
+		m.downloadsFailed.Add(1)
+	} else {
+		m.downloadsOK.Add(1)
+	}
+}
+
+// apiGet is http.Get for the emote APIs, counted in the metrics
+func (m *appMetrics) apiGet(url string) (*http.Response, error) {
+	m.apiCalls.Add(1)
+	return http.Get(url)
+}
+
+// Metrics is a snapshot of the counters
+type Metrics struct {
+	Messages        map[string]int64 `json:"messages"`
+	EmotesParsed    int64            `json:"emotesParsed"`
+	Downloads       int64            `json:"downloads"`
+	DownloadsOK     int64            `json:"downloadsOk"`
+	DownloadsFailed int64            `json:"downloadsFailed"`
+	APICalls        int64            `json:"apiCalls"`
+}
+
+// GetMetrics returns the counters accumulated since startup
+func (a *App) GetMetrics() Metrics {
+	a.metrics.mu.Lock()
+	messages := make(map[string]int64, len(a.metrics.messages))
+	for channel, n := range a.metrics.messages {
+		messages[channel] = n
+	}
+	a.metrics.mu.Unlock()
+
+	return Metrics{
+		Messages:        messages,
+		EmotesParsed:    a.metrics.emotesParsed.Load(),
+		Downloads:       a.metrics.downloads.Load(),
+		DownloadsOK:     a.metrics.downloadsOK.Load(),
+		DownloadsFailed: a.metrics.downloadsFailed.Load(),
+		APICalls:        a.metrics.apiCalls.Load(),
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Wails v2 has no native notification API, the frontend shows these with
+// the webview's Notification API
+
+// Same-key notifications within this window are dropped
+const notifyDebounce = 10 * time.Second
+
+// Longer message snippets are cut to this many runes
+const notifySnippetLen = 100
+
+// When each notification key was last sent
+type notifyLog struct {
+	sync.Mutex
+	sent map[string]time.Time
+}
+
+// notify asks the frontend for a desktop notification. key groups
+// notifications for debouncing, e.g. "highlight:#channel".
+func (a *App) notify(key, title, body string) {
+	now := time.Now()
+	a.notifyLast.Lock()
+	if last, ok := a.notifyLast.sent[key]; ok && now.Sub(last) < notifyDebounce {
+		a.notifyLast.Unlock()
+		return
+	}
+	a.notifyLast.sent[key] = now
+	a.notifyLast.Unlock()
+
+	a.emit("desktop-notification", map[string]interface{}{
+		"title": title,
+		"body":  body,
+	})
+}
+
+func snippet(text string) string {
+	runes := []rune(text)
+	if len(runes) <= notifySnippetLen {
+		return text
+	}
+	return string(runes[:notifySnippetLen]) + "…"
+}
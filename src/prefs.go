@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Runtime toggles the user changes from the UI, kept across restarts.
+// config.txt stays hand-edited only, so these live in their own file.
+type Preferences struct {
+	AlertsMuted   bool            `json:"alertsMuted"`
+	IgnoredUsers  []string        `json:"ignoredUsers,omitempty"`  // added with IgnoreUser, on top of $ignore
+	MutedChannels map[string]bool `json:"mutedChannels,omitempty"` // login -> stream audio muted, from SetChannelAudioMuted
+}
+
+func loadPreferences(path string) Preferences {
+	p := Preferences{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logWarnf("Error reading %s: %v", path, err)
+		}
+		return p
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		logWarnf("Error parsing %s: %v", path, err)
+	}
+	return p
+}
+
+// updatePreferences applies fn to the preferences and writes them to disk
+func (a *App) updatePreferences(fn func(p *Preferences)) {
+	a.prefsMu.Lock()
+	fn(&a.prefs)
+	data, err := json.MarshalIndent(a.prefs, "", "  ")
+	a.prefsMu.Unlock()
+
+	if err != nil {
+		logErrorf("Error encoding preferences: %v", err)
+		return
+	}
+	path := a.dataPath("prefs.json")
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		logErrorf("Error writing %s: %v", path, err)
+	}
+}
+
+func (a *App) getPreferences() Preferences {
+	a.prefsMu.RLock()
+	defer a.prefsMu.RUnlock()
+	return a.prefs
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ChannelProfile is what the sidebar shows for a channel. Avatar is a data
+// URL of the copy under channels/<login>/, empty if it couldn't be fetched.
+type ChannelProfile struct {
+	Login           string    `json:"login"`
+	DisplayName     string    `json:"displayName"`
+	Description     string    `json:"description"`
+	ProfileImageURL string    `json:"profileImageUrl"`
+	Avatar          string    `json:"avatar,omitempty"`
+	Fetched         time.Time `json:"fetched"`
+}
+
+// Profiles rarely change, refetch them once a day
+const profileTTL = 24 * time.Hour
+
+// GetChannelProfile returns channel's display name, description and avatar.
+// Profiles are kept in channels/<login>/profile.json so they load offline.
+func (a *App) GetChannelProfile(channel string) (ChannelProfile, error) {
+	login, err := normalizeChannelName(channel)
+	if err != nil {
+		return ChannelProfile{}, err
+	}
+
+	profilePath := a.dataPath("channels", login, "profile.json")
+	avatarPath := a.dataPath("channels", login, "avatar")
+
+	var cached ChannelProfile
+	haveCached := false
+	if data, err := os.ReadFile(profilePath); err == nil && json.Unmarshal(data, &cached) == nil {
+		haveCached = true
+		if time.Since(cached.Fetched) < profileTTL {
+			cached.Avatar = avatarDataURL(avatarPath)
+			return cached, nil
+		}
+	}
+
+	query := fmt.Sprintf(`{"query":"query { user(login:\"%s\") { login displayName description profileImageURL(width:70) } }"}`, login)
+	var result struct {
+		Data struct {
+			User *struct {
+				Login           string `json:"login"`
+				DisplayName     string `json:"displayName"`
+				Description     string `json:"description"`
+				ProfileImageURL string `json:"profileImageURL"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+	if err := a.gqlRequest(query, &result); err != nil {
+		if haveCached {
+			cached.Avatar = avatarDataURL(avatarPath)
+			return cached, nil
+		}
+		return ChannelProfile{}, err
+	}
+	user := result.Data.User
+	if user == nil {
+		return ChannelProfile{}, fmt.Errorf("%w: %s", ErrNoSuchChannel, login)
+	}
+
+	profile := ChannelProfile{
+		Login:           login,
+		DisplayName:     user.DisplayName,
+		Description:     user.Description,
+		ProfileImageURL: user.ProfileImageURL,
+		Fetched:         time.Now(),
+	}
+
+	if err := os.MkdirAll(a.dataPath("channels", login), 0755); err != nil {
+		return ChannelProfile{}, err
+	}
+	if profile.ProfileImageURL != "" && profile.ProfileImageURL != cached.ProfileImageURL {
+		if err := a.downloadAvatar(profile.ProfileImageURL, avatarPath); err != nil {
+			logWarnf("Couldn't download avatar for %s: %v", login, err)
+		}
+	}
+	if data, err := json.MarshalIndent(profile, "", "  "); err == nil {
+		if err := writeFileAtomic(profilePath, data, 0644); err != nil {
+			logWarnf("Couldn't save profile for %s: %v", login, err)
+		}
+	}
+
+	profile.Avatar = avatarDataURL(avatarPath)
+	return profile, nil
+}
+
+func (a *App) downloadAvatar(url, path string) error {
+	resp, err := a.metrics.apiGet(url)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnect, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &APIStatusError{API: url, Code: resp.StatusCode}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// avatarDataURL returns the avatar at path for an <img>, Twitch serves
+// both PNG and JPEG so the type is sniffed
+func avatarDataURL(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(data), base64.StdEncoding.EncodeToString(data))
+}
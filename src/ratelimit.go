@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket allowing up to capacity actions per window,
+// refilled continuously.
+type RateLimiter struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64 // tokens per second
+	last     time.Time
+}
+
+func NewRateLimiter(capacity int, window time.Duration) *RateLimiter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if window <= 0 {
+		window = time.Second
+	}
+	return &RateLimiter{
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		rate:     float64(capacity) / window.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+func (rl *RateLimiter) refill() {
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.capacity {
+		rl.tokens = rl.capacity
+	}
+	rl.last = now
+}
+
+// Allow consumes a token if one is available
+func (rl *RateLimiter) Allow() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill()
+	if rl.tokens < 1 {
+		return false
+	}
+	rl.tokens--
+	return true
+}
+
+// Delay returns how long until the next token is available
+func (rl *RateLimiter) Delay() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill()
+	if rl.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - rl.tokens) / rl.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available and consumes it
+func (rl *RateLimiter) Wait() {
+	for !rl.Allow() {
+		time.Sleep(rl.Delay())
+	}
+}
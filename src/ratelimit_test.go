@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingConn keeps everything the client writes
+type recordingConn struct {
+	mu  sync.Mutex
+	out bytes.Buffer
+}
+
+func (r *recordingConn) Read(p []byte) (int, error) { select {} }
+func (r *recordingConn) Close() error               { return nil }
+
+func (r *recordingConn) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.out.Write(p)
+}
+
+func TestSendMessageRateLimit(t *testing.T) {
+	conn := &recordingConn{}
+	c := newTestClient("#chan")
+	c.SetCredentials("me", "token")
+	c.sendLimiter = NewRateLimiter(20, 30*time.Second)
+	c.ConnectSource(conn)
+
+	sent, limited := 0, 0
+	for i := 0; i < 30; i++ {
+		err := c.SendMessage("hello")
+		switch {
+		case err == nil:
+			sent++
+		case errors.Is(err, ErrRateLimited):
+			limited++
+		default:
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	if sent != 20 || limited != 10 {
+		t.Errorf("sent %d and rejected %d, want 20 and 10", sent, limited)
+	}
+
+	conn.mu.Lock()
+	written := strings.Count(conn.out.String(), "PRIVMSG #chan :hello\r\n")
+	conn.mu.Unlock()
+	if written != 20 {
+		t.Errorf("%d messages reached the connection, want 20", written)
+	}
+}
+
+func TestRateLimiterDelay(t *testing.T) {
+	rl := NewRateLimiter(20, 30*time.Second)
+	for i := 0; i < 20; i++ {
+		if !rl.Allow() {
+			t.Fatalf("send %d rejected within the limit", i)
+		}
+	}
+	if rl.Allow() {
+		t.Fatal("21st send allowed")
+	}
+	// one token refills every 1.5s
+	if d := rl.Delay(); d <= 0 || d > 1500*time.Millisecond {
+		t.Errorf("Delay() = %v, want (0, 1.5s]", d)
+	}
+}
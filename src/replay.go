@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Lines from logs/<date>_rawirc.txt, as written by logRawIRC
+var rawLogLine = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2}\.\d{3})\] (#\S+) ([<>]) (.*)$`)
+
+// Lines from logs/<channel>/<date>_log.txt, as written by logMessage
+var chatLogLine = regexp.MustCompile(`^\[[^\]]*\] ([^:\s]+): (.*)$`)
+
+const (
+	// Chat logs don't keep usable timestamps, their lines go out this far apart
+	replayInterval = 250 * time.Millisecond
+	// Longer pauses in a raw log are cut down to this
+	maxReplayGap = 5 * time.Second
+)
+
+// replayLine is one line to feed to a channel's client, after wait
+type replayLine struct {
+	channel string
+	line    string
+	wait    time.Duration
+}
+
+// ReplayLog plays a saved log back through the normal message pipeline
+// (parsing, emotes, highlights, events) without connecting to Twitch. It
+// takes raw IRC logs, chat logs and files of bare IRC lines. speed scales
+// the original pacing, 2 is twice as fast, 0 or less as fast as possible.
+//
+// Each channel in the log shows up as a connection of its own until
+// disconnected. Channels that are connected for real are skipped.
+func (a *App) ReplayLog(path string, speed float64) error {
+	lines, err := readReplayLog(path)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("nothing to replay in %s", path)
+	}
+
+	sources := make(map[string]*replaySource)
+	for _, l := range lines {
+		if _, ok := sources[l.channel]; ok {
+			continue
+		}
+		src, err := a.startReplay(l.channel)
+		if err != nil {
+			logWarnf("Not replaying %s: %v", l.channel, err)
+		}
+		sources[l.channel] = src
+	}
+
+	go func() {
+		played := 0
+		for _, l := range lines {
+			src := sources[l.channel]
+			if src == nil {
+				continue
+			}
+			if speed > 0 && l.wait > 0 {
+				time.Sleep(time.Duration(float64(l.wait) / speed))
+			}
+			// forwardMessages drops what doesn't fit, let it catch up
+			for len(src.client.messageChan) == cap(src.client.messageChan) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if _, err := fmt.Fprintf(src.w, "%s\r\n", l.line); err != nil {
+				// disconnected mid-replay
+				continue
+			}
+			played++
+		}
+		for _, src := range sources {
+			if src != nil {
+				src.w.Close()
+			}
+		}
+		logInfof("Replayed %d lines from %s", played, path)
+		a.emit("replay-finished", map[string]interface{}{
+			"path":  path,
+			"lines": played,
+		})
+	}()
+	return nil
+}
+
+// replaySource is the writing end of a replayed channel's client source
+type replaySource struct {
+	client *Client
+	w      *io.PipeWriter
+}
+
+// startReplay sets up a connection for channel whose client reads what
+// ReplayLog writes instead of a socket
+func (a *App) startReplay(channel string) (*replaySource, error) {
+	a.connectionsMu.Lock()
+	defer a.connectionsMu.Unlock()
+	if _, exists := a.connections[channel]; exists {
+		return nil, fmt.Errorf("%s is already connected", channel)
+	}
+
+	size := int(a.bufferSize.Load())
+	conn := &ChannelConnection{
+		channel:     channel,
+		bufferSize:  size,
+		messages:    make([]map[string]interface{}, 0, size),
+		viewers:     NewViewerHistory(viewerHistorySize),
+		recent:      make(map[string]*repeatedLine),
+		isConnected: true,
+		replay:      true,
+	}
+	pr, pw := io.Pipe()
+	conn.client = a.newClient(channel, size)
+	conn.client.ConnectSource(pr)
+	conn.client.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn.cancel = cancel
+	a.connections[channel] = conn
+	if a.activeChannel == "" {
+		a.activeChannel = channel
+	}
+
+	go a.forwardMessages(ctx, conn)
+	a.emit("channel-connected", channel)
+	return &replaySource{client: conn.client, w: pw}, nil
+}
+
+// readReplayLog reads path into the lines to replay. Outgoing raw lines
+// and anything unrecognised are skipped.
+func readReplayLog(path string) ([]replayLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// chat logs live in logs/<channel>/
+	logChannel := "#" + strings.ToLower(filepath.Base(filepath.Dir(path)))
+
+	var lines []replayLine
+	var last time.Time
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxIRCLineSize)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+
+		if m := rawLogLine.FindStringSubmatch(text); m != nil {
+			if m[3] != "<" {
+				continue
+			}
+			var wait time.Duration
+			if t, err := time.Parse("15:04:05.000", m[1]); err == nil {
+				if !last.IsZero() {
+					wait = min(max(t.Sub(last), 0), maxReplayGap)
+				}
+				last = t
+			}
+			lines = append(lines, replayLine{channel: strings.ToLower(m[2]), line: m[4], wait: wait})
+			continue
+		}
+
+		if strings.HasPrefix(text, "@") || strings.HasPrefix(text, ":") {
+			if channel := lineChannel(text); channel != "" {
+				lines = append(lines, replayLine{channel: channel, line: text, wait: replayInterval})
+			}
+			continue
+		}
+
+		if m := chatLogLine.FindStringSubmatch(text); m != nil {
+			if _, err := normalizeChannelName(logChannel); err != nil {
+				return nil, fmt.Errorf("can't tell the channel of chat log %s, expected it under logs/<channel>/", path)
+			}
+			login := strings.ToLower(m[1])
+			line := fmt.Sprintf("@display-name=%s :%s!%s@%s.tmi.twitch.tv PRIVMSG %s :%s",
+				m[1], login, login, login, logChannel, m[2])
+			lines = append(lines, replayLine{channel: logChannel, line: line, wait: replayInterval})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
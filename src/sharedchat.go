@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Logins of shared chat partner channels by room id. Partners we aren't
+// connected to are looked up once, in the background.
+type roomLogins struct {
+	sync.Mutex
+	logins  map[string]string
+	pending map[string]bool
+}
+
+// sourceChannel returns the login for a shared chat source room, or "" while
+// it's still being looked up
+func (a *App) sourceChannel(roomID string) string {
+	a.connectionsMu.RLock()
+	for name, conn := range a.connections {
+		conn.mu.RLock()
+		id := conn.roomID
+		conn.mu.RUnlock()
+		if id == roomID {
+			a.connectionsMu.RUnlock()
+			return strings.TrimPrefix(name, "#")
+		}
+	}
+	a.connectionsMu.RUnlock()
+
+	a.sharedChatRooms.Lock()
+	defer a.sharedChatRooms.Unlock()
+	if login, ok := a.sharedChatRooms.logins[roomID]; ok {
+		return login
+	}
+	if !a.sharedChatRooms.pending[roomID] {
+		a.sharedChatRooms.pending[roomID] = true
+		go a.lookupRoom(roomID)
+	}
+	return ""
+}
+
+func (a *App) lookupRoom(roomID string) {
+	query := fmt.Sprintf(`{"query":"query { user(id:\"%s\") { login } }"}`, roomID)
+	var result struct {
+		Data struct {
+			User *struct {
+				Login string `json:"login"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+
+	err := a.gqlRequest(query, &result)
+
+	a.sharedChatRooms.Lock()
+	defer a.sharedChatRooms.Unlock()
+	delete(a.sharedChatRooms.pending, roomID)
+	if err != nil {
+		// try again on the next message
+		logDebugf("Couldn't look up shared chat room %s: %v", roomID, err)
+		return
+	}
+	login := ""
+	if result.Data.User != nil {
+		login = strings.ToLower(result.Data.User.Login)
+	}
+	a.sharedChatRooms.logins[roomID] = login
+}
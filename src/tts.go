@@ -3,20 +3,32 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"log"
+	"hash/fnv"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ebitengine/oto/v3"
+	"github.com/go-audio/audio"
 	"github.com/go-audio/wav"
 )
 
+// oto only allows a single context per process, so every sound we play
+// (piper TTS output and the alert ding) is converted to this format first.
+// piper's medium voices render 22050Hz mono, so that's what we open with.
+const (
+	otoSampleRate   = 22050
+	otoChannelCount = 1
+)
+
 func initOto() (*oto.Context, error) {
 	op := &oto.NewContextOptions{
-		SampleRate:   22050,
-		ChannelCount: 1,
+		SampleRate:   otoSampleRate,
+		ChannelCount: otoChannelCount,
 		Format:       oto.FormatSignedInt16LE,
 	}
 	otoCtx, _, err := oto.NewContext(op)
@@ -26,8 +38,34 @@ func initOto() (*oto.Context, error) {
 	return otoCtx, nil
 }
 
-func generateTTSFiles() error {
+// Same paths generate_tts.bat uses
+const (
+	piperExe   = "tools/piper/piper.exe"
+	piperModel = "tools/piper/en_US-joe-medium.onnx"
+)
+
+// initTTS checks for piper and generates the per-channel announcements.
+// Missing TTS assets are only a warning, they never stop the app.
+func (a *App) initTTS() {
+	for _, path := range []string{piperExe, piperModel} {
+		if _, err := os.Stat(path); err != nil {
+			logWarnf("TTS disabled, %s not found", path)
+			return
+		}
+	}
+	a.ttsAvailable = true
+
+	if err := a.generateTTSFiles(); err != nil {
+		logWarnf("%v", err)
+	}
+	a.generateTTSOverrides()
+}
+
+func (a *App) generateTTSFiles() error {
 	cmd := exec.Command("cmd", "/C", "generate_tts.bat")
+	cmd.Env = append(os.Environ(),
+		"WATCHERINO_CONFIG="+a.configPath,
+		"WATCHERINO_TTSPATH="+a.ttsDir()+string(filepath.Separator))
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -36,49 +74,155 @@ func generateTTSFiles() error {
 	return nil
 }
 
-func getWavForChannel(channel string) []byte {
-	cfg := GetTwitchConfigFromFile("config.txt")
-	ttspath := cfg.TTSPath
-	if ttspath == "" {
-		ttspath = "tts"
+// synthesizeTTS renders text with piper into a temp file and returns the WAV
+func (a *App) synthesizeTTS(text string) ([]byte, error) {
+	if !a.ttsAvailable {
+		return nil, fmt.Errorf("TTS is not available, check %s and %s", piperExe, piperModel)
+	}
+
+	tmp, err := os.CreateTemp("", "watcherino-tts-*.wav")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	cmd := exec.Command(piperExe, "--model", piperModel, "--output_file", tmp.Name())
+	cmd.Stdin = strings.NewReader(text)
+	if runtime.GOOS == "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("piper failed: %w: %s", err, strings.TrimSpace(string(out)))
 	}
 
-	fileName := filepath.Join(ttspath, channel+".wav")
+	return os.ReadFile(tmp.Name())
+}
+
+// ttsDir is where the per-channel announcements are, $ttspath or <datadir>/tts
+func (a *App) ttsDir() string {
+	if a.config.TTSPath != "" {
+		return a.config.TTSPath
+	}
+	return a.dataPath("tts")
+}
+
+// ttsFile is channel's announcement. Channels with a ttsmsg: override get
+// the message's hash in the name, so editing it generates a new file.
+func (a *App) ttsFile(channel string) string {
+	message, ok := a.config.TTSMessages[channel]
+	if !ok {
+		return filepath.Join(a.ttsDir(), channel+".wav")
+	}
+	h := fnv.New32a()
+	h.Write([]byte(message))
+	return filepath.Join(a.ttsDir(), fmt.Sprintf("%s_%08x.wav", channel, h.Sum32()))
+}
+
+// generateTTSOverrides renders the ttsmsg: announcements that don't exist
+// yet, generate_tts.bat only knows $ttsmessage
+func (a *App) generateTTSOverrides() {
+	for channel, message := range a.config.TTSMessages {
+		path := a.ttsFile(channel)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		wav, err := a.synthesizeTTS(channel + " " + message)
+		if err != nil {
+			logWarnf("Couldn't generate TTS for %s: %v", channel, err)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			logWarnf("Couldn't generate TTS for %s: %v", channel, err)
+			continue
+		}
+		if err := writeFileAtomic(path, wav, 0644); err != nil {
+			logWarnf("Couldn't save TTS for %s: %v", channel, err)
+			continue
+		}
+		logInfof("Generated TTS for %s: %s", channel, path)
+	}
+}
+
+func (a *App) getWavForChannel(channel string) []byte {
+	fileName := a.ttsFile(channel)
 	body, err := os.ReadFile(fileName)
 	if err != nil {
-		log.Printf("Error reading TTS file %s: %v\n", fileName, err)
+		// already warned about in initTTS
+		if !a.ttsAvailable && os.IsNotExist(err) {
+			return nil
+		}
+		logWarnf("Error reading TTS file %s: %v\n", fileName, err)
 		return nil
 	}
 	return body
 }
 
 // getMp3ForChannel kept for compatibility
-func getMp3ForChannel(channel string) []byte {
-	return getWavForChannel(channel)
+func (a *App) getMp3ForChannel(channel string) []byte {
+	return a.getWavForChannel(channel)
+}
+
+// QuietHours is a daily window during which alerts don't play.
+// End may be before Start for windows that cross midnight.
+type QuietHours struct {
+	Start int // minutes since midnight
+	End   int
+	Set   bool
+}
+
+func (q QuietHours) Contains(t time.Time) bool {
+	if !q.Set {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if q.Start <= q.End {
+		return now >= q.Start && now < q.End
+	}
+	return now >= q.Start || now < q.End
+}
+
+func (a *App) alertsMuted() bool {
+	return a.getPreferences().AlertsMuted || a.config.QuietHours.Contains(time.Now())
+}
+
+// playAlert plays a live/highlight alert unless alerts are muted.
+// Stream audio has its own mute (App.audioMuted) and isn't affected.
+func (a *App) playAlert(file []byte, volume float64) {
+	if a.alertsMuted() {
+		return
+	}
+	a.playWav(file, volume)
+}
+
+func (a *App) playWav(file []byte, volume float64) {
+	// No audio device (headless, RDP, WSL), chat still works without sound
+	if a.audioOut == nil {
+		a.audioWarnOnce.Do(func() {
+			logWarnf("audio output unavailable, alerts are disabled: %v", a.audioOutErr)
+		})
+		return
+	}
+	playWav(a.audioOut, file, volume)
 }
 
 func playWav(otoCtx *oto.Context, file []byte, volume float64) {
 	if len(file) == 0 {
-		log.Println("Warning: Empty WAV data, skipping playback")
+		logWarnf("Empty WAV data, skipping playback")
 		return
 	}
 	fileBytesReader := bytes.NewReader(file)
 	decoder := wav.NewDecoder(fileBytesReader)
 	if !decoder.IsValidFile() {
-		log.Println("Warning: Invalid WAV file, skipping playback")
+		logWarnf("Invalid WAV file, skipping playback")
 		return
 	}
 	buf, err := decoder.FullPCMBuffer()
 	if err != nil {
-		log.Printf("Warning: failed to decode WAV: %s\n", err.Error())
+		logWarnf("failed to decode WAV: %s\n", err.Error())
 		return
 	}
-	pcmData := make([]byte, len(buf.Data)*2)
-	for i, sample := range buf.Data {
-		s := int16(sample)
-		pcmData[i*2] = byte(s)
-		pcmData[i*2+1] = byte(s >> 8)
-	}
+	pcmData := convertPCM(buf)
 	player := otoCtx.NewPlayer(bytes.NewReader(pcmData))
 	player.SetVolume(volume)
 	player.Play()
@@ -86,6 +230,75 @@ func playWav(otoCtx *oto.Context, file []byte, volume float64) {
 		time.Sleep(time.Millisecond)
 	}
 	if err := player.Close(); err != nil {
-		log.Printf("Warning: player.Close failed: %s\n", err.Error())
+		logWarnf("player.Close failed: %s\n", err.Error())
+	}
+}
+
+// convertPCM downmixes, resamples and requantizes a decoded WAV buffer to the
+// otoCtx format (otoSampleRate, otoChannelCount, signed 16-bit LE).
+// Without this a 48kHz stereo file would play back at roughly 4x speed.
+func convertPCM(buf *audio.IntBuffer) []byte {
+	srcChannels := 1
+	srcRate := otoSampleRate
+	if buf.Format != nil {
+		if buf.Format.NumChannels > 0 {
+			srcChannels = buf.Format.NumChannels
+		}
+		if buf.Format.SampleRate > 0 {
+			srcRate = buf.Format.SampleRate
+		}
+	}
+
+	frames := len(buf.Data) / srcChannels
+	mono := make([]float64, frames)
+	for i := 0; i < frames; i++ {
+		sum := 0
+		for ch := 0; ch < srcChannels; ch++ {
+			sum += buf.Data[i*srcChannels+ch]
+		}
+		mono[i] = to16Bit(float64(sum)/float64(srcChannels), buf.SourceBitDepth)
+	}
+
+	outFrames := frames
+	if srcRate != otoSampleRate {
+		outFrames = int(int64(frames) * int64(otoSampleRate) / int64(srcRate))
+	}
+
+	pcmData := make([]byte, 0, outFrames*otoChannelCount*2)
+	step := float64(srcRate) / float64(otoSampleRate)
+	for i := 0; i < outFrames; i++ {
+		// linear interpolation between the two nearest source frames
+		pos := float64(i) * step
+		idx := int(pos)
+		sample := mono[idx]
+		if idx+1 < frames {
+			frac := pos - float64(idx)
+			sample += (mono[idx+1] - sample) * frac
+		}
+
+		if sample > 32767 {
+			sample = 32767
+		} else if sample < -32768 {
+			sample = -32768
+		}
+		s := int16(sample)
+		for ch := 0; ch < otoChannelCount; ch++ {
+			pcmData = append(pcmData, byte(s), byte(s>>8))
+		}
+	}
+	return pcmData
+}
+
+// to16Bit scales a sample decoded at bitDepth to the signed 16-bit range.
+func to16Bit(sample float64, bitDepth int) float64 {
+	switch bitDepth {
+	case 8:
+		// 8-bit WAV is unsigned
+		return (sample - 128) * 256
+	case 24:
+		return sample / 256
+	case 32:
+		return sample / 65536
 	}
+	return sample
 }
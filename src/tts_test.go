@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+// writeTestWav encodes frames of a constant sample per channel and decodes
+// it again the way playWav does
+func writeTestWav(t *testing.T, rate, bitDepth int, frames int, channelValues []int) *audio.IntBuffer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := wav.NewEncoder(f, rate, bitDepth, len(channelValues), 1)
+	data := make([]int, 0, frames*len(channelValues))
+	for i := 0; i < frames; i++ {
+		data = append(data, channelValues...)
+	}
+	buf := &audio.IntBuffer{
+		Format:         &audio.Format{NumChannels: len(channelValues), SampleRate: rate},
+		Data:           data,
+		SourceBitDepth: bitDepth,
+	}
+	if err := enc.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := wav.NewDecoder(bytes.NewReader(file))
+	if !dec.IsValidFile() {
+		t.Fatal("encoded WAV isn't valid")
+	}
+	decoded, err := dec.FullPCMBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestConvertPCM(t *testing.T) {
+	tests := []struct {
+		name          string
+		rate          int
+		bitDepth      int
+		frames        int
+		channelValues []int
+		wantFrames    int
+		wantSample    int16
+	}{
+		{"22050Hz mono 16-bit passes through", 22050, 16, 2205, []int{1000}, 2205, 1000},
+		{"48kHz stereo 16-bit", 48000, 16, 4800, []int{1000, 3000}, 2205, 2000},
+		{"44100Hz mono 16-bit", 44100, 16, 4410, []int{-500}, 2205, -500},
+		{"44100Hz stereo 24-bit", 44100, 24, 4410, []int{256000, 256000}, 2205, 1000},
+		{"8-bit is unsigned", 22050, 8, 2205, []int{129}, 2205, 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := writeTestWav(t, tt.rate, tt.bitDepth, tt.frames, tt.channelValues)
+			pcm := convertPCM(buf)
+
+			// 0.1s of audio has to stay 0.1s at otoSampleRate, or it plays
+			// at the wrong speed and pitch
+			if got := len(pcm) / (2 * otoChannelCount); got != tt.wantFrames {
+				t.Fatalf("got %d frames, want %d", got, tt.wantFrames)
+			}
+			for i := 0; i < len(pcm); i += 2 {
+				if got := int16(binary.LittleEndian.Uint16(pcm[i:])); got != tt.wantSample {
+					t.Fatalf("sample %d = %d, want %d", i/2, got, tt.wantSample)
+				}
+			}
+		})
+	}
+}
@@ -2,27 +2,84 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"io"
+	"math/rand/v2"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Message represents a parsed Twitch chat message
 type Message struct {
-	Username     string
-	Content      string
-	Channel      string
-	Tags         map[string]string
-	RawData      string
-	Timestamp    time.Time
-	Height       int
-	UserColor    string
-	isUserNotice bool
+	Username           string // display name, may differ from Login in more than case
+	Login              string // lowercase account name
+	UserID             string // stable across renames, empty if the server didn't send tags
+	Content            string
+	Channel            string
+	Tags               map[string]string
+	RawData            string
+	Timestamp          time.Time
+	Height             int
+	UserColor          string
+	Badges             []Badge
+	IsFirstMessage     bool
+	IsReturningChatter bool
+	IsAction           bool
+	isUserNotice       bool
+
+	// Set when the message was sent in another channel of a shared chat
+	// session. Empty for normal messages.
+	SourceRoomID    string
+	SourceMessageID string
+}
+
+// Badge is a chat badge from the badges tag, e.g. moderator/1 or subscriber/3012.
+// Info is the matching badge-info value where Twitch sends one (sub months).
+type Badge struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	Info    string `json:"info,omitempty"`
+}
+
+// parseBadges parses the badges and badge-info tags,
+// format: badges=broadcaster/1,subscriber/3012 badge-info=subscriber/14
+func parseBadges(badges, badgeInfo string) []Badge {
+	if badges == "" {
+		return nil
+	}
+
+	info := make(map[string]string)
+	for _, b := range strings.Split(badgeInfo, ",") {
+		if kv := strings.SplitN(b, "/", 2); len(kv) == 2 {
+			info[kv[0]] = kv[1]
+		}
+	}
+
+	var result []Badge
+	for _, b := range strings.Split(badges, ",") {
+		kv := strings.SplitN(b, "/", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		result = append(result, Badge{Type: kv[0], Version: kv[1], Info: info[kv[0]]})
+	}
+	return result
+}
+
+func (msg *Message) HasBadge(badgeType string) bool {
+	for _, b := range msg.Badges {
+		if b.Type == badgeType {
+			return true
+		}
+	}
+	return false
 }
 
 func (msg *Message) GetRoomID() string {
@@ -42,6 +99,26 @@ type RewardRedemption struct {
 	Timestamp  time.Time
 }
 
+// Notice represents a NOTICE from the Twitch server (room mode changes,
+// "your message was not sent", etc.)
+type Notice struct {
+	Channel   string
+	MsgID     string
+	Content   string
+	Timestamp time.Time
+}
+
+// Whisper is a private message to the logged in user. They aren't tied to
+// a channel and only arrive on authenticated connections.
+type Whisper struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"` // display name
+	FromLogin string    `json:"fromLogin"`
+	To        string    `json:"to"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // RingBuffer holds the last N messages
 type RingBuffer struct {
 	messages []Message
@@ -100,63 +177,304 @@ func (rb *RingBuffer) GetLast(n int) []Message {
 
 // Client represents a Twitch IRC client
 type Client struct {
-	conn          net.Conn
+	conn          ircConn
+	fromSource    bool // conn came from ConnectSource, don't redial when it ends
 	username      string
+	oauthToken    string
 	channel       string
 	messageBuffer *RingBuffer
 	rewardChan    chan RewardRedemption
 	messageChan   chan Message
+	noticeChan    chan Notice
+	whisperChan   chan Whisper
+	emoteSetsChan chan []string
+	modActionChan chan ModAction
+	presenceChan  chan Presence
+	rawChan       chan RawLine
 	errorChan     chan error
+	readyChan     chan struct{}
 	stopChan      chan struct{}
+	sendLimiter   *RateLimiter
+	mux           *ircMux // shared connection with $multiplex, nil for our own
+	joinLimiter   *RateLimiter
+	rawIRC        *atomic.Bool // send every line on rawChan, toggled with SetRawIRC
+	config        *TwitchConfig
 	mu            sync.RWMutex
 	connected     bool
+	joined        bool
 	stopped       bool
+	nickRetries   int
+	lastRead      atomic.Int64 // unix nanos of the last line from the server
+}
+
+// JoinError is sent on the error channel when Twitch refuses the JOIN,
+// e.g. the channel is suspended or we're banned from it.
+type JoinError struct {
+	Channel string
+	MsgID   string
+	Reason  string
 }
 
-func NewClient(channel string, bufferSize int) *Client {
+func (e *JoinError) Error() string {
+	return fmt.Sprintf("join %s failed: %s (%s)", e.Channel, e.Reason, e.MsgID)
+}
+
+// NOTICE msg-ids that mean the JOIN didn't go through and chat won't arrive
+var joinFailureMsgIDs = map[string]bool{
+	"msg_channel_suspended": true,
+	"msg_channel_blocked":   true,
+	"msg_banned":            true,
+	"tos_ban":               true,
+}
+
+// NewClient returns a client on its own connection, with its own JOIN rate
+// limit. The app's clients share those, see App.newClient.
+func NewClient(channel string, bufferSize int, config *TwitchConfig) *Client {
+	rawIRC := new(atomic.Bool)
+	rawIRC.Store(config.RawIRC)
 	return &Client{
 		channel:       channel,
 		messageBuffer: NewRingBuffer(bufferSize),
 		rewardChan:    make(chan RewardRedemption, 100),
 		messageChan:   make(chan Message, 100),
+		noticeChan:    make(chan Notice, 10),
+		whisperChan:   make(chan Whisper, 10),
+		emoteSetsChan: make(chan []string, 1),
+		modActionChan: make(chan ModAction, 50),
+		presenceChan:  make(chan Presence, 100),
+		rawChan:       make(chan RawLine, 200),
 		errorChan:     make(chan error, 10),
+		readyChan:     make(chan struct{}, 1),
 		stopChan:      make(chan struct{}),
+		sendLimiter:   NewRateLimiter(config.SendRateLimit, config.SendRateWindow),
+		joinLimiter:   NewRateLimiter(config.JoinRateLimit, config.JoinRateWindow),
+		rawIRC:        rawIRC,
+		config:        config,
 	}
 }
 
-func (c *Client) Connect() error {
-	server := "irc.chat.twitch.tv"
-	port := 6667
+var (
+	ErrNotAuthenticated = errors.New("not authenticated, sending requires $nick and $oauth")
+	ErrRateLimited      = errors.New("message rate limit reached")
+)
+
+// SetCredentials makes the next Connect log in as nick instead of an
+// anonymous justinfan user.
+func (c *Client) SetCredentials(nick, oauthToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username = strings.ToLower(nick)
+	c.oauthToken = oauthToken
+}
+
+// ircConn is what a Client reads server lines from and writes commands to.
+// Connect dials Twitch for a net.Conn; ConnectSource takes anything else.
+type ircConn interface {
+	io.ReadWriteCloser
+}
+
+// sourceConn adapts a plain reader, writes go nowhere
+type sourceConn struct {
+	io.Reader
+}
+
+func (s sourceConn) Write(p []byte) (int, error) { return len(p), nil }
 
+func (s sourceConn) Close() error {
+	if closer, ok := s.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// setReadDeadline pushes back the idle timeout on conns that support one
+func setReadDeadline(conn ircConn) {
+	if d, ok := conn.(interface{ SetReadDeadline(time.Time) error }); ok {
+		d.SetReadDeadline(time.Now().Add(readIdleTimeout))
+	}
+}
+
+// dialIRC opens a connection to the configured server, over TLS with $irctls
+func dialIRC(config *TwitchConfig) (net.Conn, error) {
+	port := config.IRCPort
+	if port == 0 {
+		port = 6667
+		if config.IRCTLS {
+			port = 6697
+		}
+	}
+	addr := net.JoinHostPort(config.IRCServer, strconv.Itoa(port))
+
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if config.IRCTLS {
+		conn, err = tls.DialWithDialer(d, "tcp", addr, &tls.Config{ServerName: config.IRCServer})
+	} else {
+		conn, err = d.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w: %w", addr, ErrConnect, err)
+	}
+	return conn, nil
+}
+
+// registerIRC requests the capabilities, membership ones too with
+// $membership, and logs in. raw gets every line sent, for the debug stream.
+func registerIRC(conn net.Conn, nick, oauthToken string, membership bool, raw func(string, bool)) {
+	caps := "twitch.tv/tags twitch.tv/commands"
+	if membership {
+		caps += " twitch.tv/membership"
+	}
+	send := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+		raw(line, true)
+	}
+	send("CAP REQ :" + caps)
+	if oauthToken != "" {
+		send("PASS " + oauthToken)
+	}
+	send("NICK " + nick)
+}
+
+// Anonymous logins are justinfan followed by a number from this range. Wide
+// enough that connections opened together don't pick the same one.
+const (
+	anonNickMin = 10000
+	anonNickMax = 99999999
+)
+
+// Times a rejected anonymous nick is replaced before giving up
+const maxNickRetries = 3
+
+func anonymousNick() string {
+	return fmt.Sprintf("justinfan%d", anonNickMin+rand.IntN(anonNickMax-anonNickMin))
+}
+
+// credentials returns the login to use, picking an anonymous one if unset
+func (c *Client) credentials() (string, string) {
 	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.username == "" {
-		c.username = fmt.Sprintf("justinfan%d", rand.Intn(9999-1000)+1000)
+		c.username = anonymousNick()
 	}
+	return c.username, c.oauthToken
+}
+
+// retryNick picks a new anonymous nick after the server refused ours (433)
+// and joins again with it. A nick that came with $oauth can't be swapped.
+func (c *Client) retryNick() {
+	c.mu.Lock()
+	conn := c.conn
+	if c.oauthToken != "" || conn == nil || c.nickRetries >= maxNickRetries {
+		nick := c.username
+		c.mu.Unlock()
+		select {
+		case c.errorChan <- fmt.Errorf("nick %s rejected by the server", nick):
+		default:
+		}
+		return
+	}
+	c.nickRetries++
+	c.username = anonymousNick()
+	nick := c.username
 	c.mu.Unlock()
 
-	d := net.Dialer{Timeout: 10 * time.Second}
-	conn, err := d.Dial("tcp", net.JoinHostPort(server, fmt.Sprintf("%d", port)))
+	logWarnf("Nick rejected on %s, retrying as %s", c.channel, nick)
+	fmt.Fprintf(conn, "NICK %s\r\n", nick)
+	c.sendRaw("NICK "+nick, true)
+	// don't hold up reading while waiting on the limiter
+	go func() {
+		c.joinLimiter.Wait()
+		fmt.Fprintf(conn, "JOIN %s\r\n", c.channel)
+		c.sendRaw("JOIN "+c.channel, true)
+	}()
+}
+
+func (c *Client) Connect() error {
+	if c.mux != nil {
+		return c.mux.join(c)
+	}
+
+	nick, oauthToken := c.credentials()
+	conn, err := dialIRC(c.config)
 	if err != nil {
-		return fmt.Errorf("dial failed: %w", err)
+		return err
 	}
 
-	fmt.Fprintf(conn, "CAP REQ :twitch.tv/tags twitch.tv/commands\r\n")
-	fmt.Fprintf(conn, "NICK %s\r\n", c.username)
+	registerIRC(conn, nick, oauthToken, c.config.Membership, c.sendRaw)
+	c.joinLimiter.Wait()
 	fmt.Fprintf(conn, "JOIN %s\r\n", c.channel)
+	c.sendRaw("JOIN "+c.channel, true)
 
+	c.attach(conn)
+	return nil
+}
+
+// ConnectSource reads server lines from src instead of dialing Twitch, for
+// tests and ReplayLog. Nothing is sent to log in; if src is also a writer it
+// gets PONGs and whatever else the client sends. The client disconnects for
+// good once src runs out.
+func (c *Client) ConnectSource(src io.Reader) {
+	conn, ok := src.(ircConn)
+	if !ok {
+		conn = sourceConn{src}
+	}
 	c.mu.Lock()
-	if c.conn != nil {
+	c.mux = nil
+	c.fromSource = true
+	c.mu.Unlock()
+	c.attach(conn)
+}
+
+// attach makes conn the client's connection, waiting for a new JOIN
+// confirmation
+func (c *Client) attach(conn ircConn) {
+	c.mu.Lock()
+	if c.conn != nil && c.mux == nil {
 		c.conn.Close()
 	}
 	c.conn = conn
 	c.connected = true
+	c.joined = false
 	c.mu.Unlock()
-
-	return nil
 }
 
 func (c *Client) Start() {
+	if c.mux != nil {
+		// the mux reads for every channel on the connection
+		return
+	}
 	go c.listen()
+	go c.keepalive()
+}
+
+// Twitch only PINGs every few minutes, so we PING ourselves to get regular
+// traffic. Going readIdleTimeout without any line means the socket is dead
+// (e.g. half-open after a network change) and listen reconnects.
+const (
+	keepaliveInterval = 60 * time.Second
+	readIdleTimeout   = keepaliveInterval + 20*time.Second
+)
+
+func (c *Client) keepalive() {
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.RLock()
+		conn := c.conn
+		connected := c.connected
+		c.mu.RUnlock()
+		if conn != nil && connected {
+			fmt.Fprint(conn, "PING :tmi.twitch.tv\r\n")
+		}
+	}
 }
 
 func (c *Client) listen() {
@@ -171,78 +489,69 @@ func (c *Client) listen() {
 		}
 
 		scanner := bufio.NewScanner(conn)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxIRCLineSize)
+		scanner.Split(scanIRCLines)
+		setReadDeadline(conn)
+		serverReconnect := false
 		for scanner.Scan() {
+			setReadDeadline(conn)
+			c.lastRead.Store(time.Now().UnixNano())
 			data := scanner.Text()
+			if data == "" {
+				continue
+			}
+			c.sendRaw(data, false)
 
-			if strings.HasPrefix(data, "PING") {
-				fmt.Fprintf(conn, "PONG :tmi.twitch.tv\r\n")
+			if ircCommand(data) == "PING" {
+				fmt.Fprint(conn, pongFor(data))
 				continue
 			}
-			var msg *Message
-
-			// Route based on command type
-			if strings.Contains(data, " PRIVMSG ") {
-				if strings.Contains(data, "custom-reward-id=") {
-					reward := c.parseRewardRedemption(data)
-					if reward != nil {
-						select {
-						case c.rewardChan <- *reward:
-						default:
-						}
-					}
-					continue
-				}
-				msg = c.parsePrivMsg(data)
-			} else if strings.Contains(data, " CLEARCHAT ") {
-				msg = c.parseClearChat(data)
-			} else if strings.Contains(data, " USERNOTICE ") {
-				msg = c.parseUserNotice(data)
+			if isReconnect(data) {
+				serverReconnect = true
+				break
 			}
+			c.handleLine(data)
+		}
 
-			if msg != nil {
-				c.messageBuffer.Add(*msg)
-				select {
-				case c.messageChan <- *msg:
-				default:
-				}
+		if err := scanner.Err(); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				logWarnf("Nothing from %s in %v, assuming the connection is dead", c.channel, readIdleTimeout)
+				conn.Close()
+			} else {
+				logWarnf("Read error for %s: %v", c.channel, err)
 			}
 		}
 
-		// 	if strings.Contains(data, "PRIVMSG") {
-		// 		if strings.Contains(data, "custom-reward-id=") {
-		// 			reward := c.parseRewardRedemption(data)
-		// 			if reward != nil {
-		// 				select {
-		// 				case c.rewardChan <- *reward:
-		// 				default:
-		// 				}
-		// 			}
-		// 		} else {
-		// 			msg := c.parsePrivMsg(data)
-		// 			if msg != nil {
-		// 				c.messageBuffer.Add(*msg)
-		// 				select {
-		// 				case c.messageChan <- *msg:
-		// 				default:
-		// 				}
-		// 			}
-		// 		}
-		// 	}
-		// }
-
 		c.mu.Lock()
 		if c.stopped {
 			c.mu.Unlock()
 			return
 		}
 		c.connected = false
+		fromSource := c.fromSource
 		c.mu.Unlock()
 
-		log.Printf("Connection lost for %s, reconnecting...", c.channel)
+		if fromSource {
+			logInfof("Source for %s ended", c.channel)
+			return
+		}
+
+		// the old connection still works until the new one is up, Connect
+		// closes it once it has replaced it. Only back off if that fails.
+		if serverReconnect {
+			logInfof("Twitch asked %s to reconnect", c.channel)
+			if err := c.Connect(); err == nil {
+				logInfof("Reconnected to %s", c.channel)
+				continue
+			}
+		}
+
+		logWarnf("Connection lost for %s, reconnecting...", c.channel)
 		for {
 			time.Sleep(5 * time.Second)
 			if err := c.Connect(); err == nil {
-				log.Printf("Reconnected to %s", c.channel)
+				logInfof("Reconnected to %s", c.channel)
 				break
 			}
 			c.mu.RLock()
@@ -255,6 +564,268 @@ func (c *Client) listen() {
 	}
 }
 
+// handleLine parses one line from the server and hands it to the matching
+// channel. PINGs are answered by whoever owns the socket.
+func (c *Client) handleLine(data string) {
+	var msg *Message
+	command := ircCommand(data)
+
+	// Route based on command type
+	if command == "PRIVMSG" {
+		msg = c.parsePrivMsg(data)
+		// Redemptions with text only go out as a reward, not also as
+		// chat. The tag is present but empty on ordinary messages.
+		if msg != nil && msg.Tags["custom-reward-id"] != "" {
+			select {
+			case c.rewardChan <- *rewardFromMessage(msg, data):
+			default:
+			}
+			return
+		}
+	} else if command == "433" {
+		// ERR_NICKNAMEINUSE
+		c.retryNick()
+		return
+	} else if command == "366" {
+		// End of NAMES, sent once the JOIN has gone through
+		c.markJoined()
+		return
+	} else if command == "NOTICE" {
+		if err := c.parseJoinFailure(data); err != nil {
+			select {
+			case c.errorChan <- err:
+			default:
+			}
+			return
+		}
+		if notice := c.parseNotice(data); notice != nil {
+			select {
+			case c.noticeChan <- *notice:
+			default:
+			}
+		}
+		return
+	} else if command == "WHISPER" {
+		c.mu.RLock()
+		authenticated := c.oauthToken != ""
+		c.mu.RUnlock()
+		if !authenticated {
+			return
+		}
+		if w := c.parseWhisper(data); w != nil {
+			select {
+			case c.whisperChan <- *w:
+			default:
+			}
+		}
+		return
+	} else if command == "USERSTATE" || command == "GLOBALUSERSTATE" {
+		// sent on login and JOIN, lists the emote sets we can use
+		c.mu.RLock()
+		authenticated := c.oauthToken != ""
+		c.mu.RUnlock()
+		tags, _ := splitTags(data)
+		if authenticated && tags["emote-sets"] != "" {
+			select {
+			case c.emoteSetsChan <- strings.Split(tags["emote-sets"], ","):
+			default:
+			}
+		}
+		return
+	} else if command == "CLEARCHAT" {
+		c.sendModAction(data)
+		msg = c.parseClearChat(data)
+	} else if command == "CLEARMSG" {
+		c.sendModAction(data)
+		return
+	} else if command == "USERNOTICE" {
+		msg = c.parseUserNotice(data)
+	} else if c.config.Membership && (command == "JOIN" || command == "PART" || command == "353") {
+		for _, p := range c.parsePresence(data) {
+			select {
+			case c.presenceChan <- p:
+			default:
+			}
+		}
+		return
+	}
+
+	if msg != nil {
+		c.messageBuffer.Add(*msg)
+		select {
+		case c.messageChan <- *msg:
+		default:
+		}
+	}
+}
+
+// A line carrying a lot of emote/badge tags can get past bufio's 64KB default,
+// which stops the scanner with ErrTooLong and drops the connection.
+const maxIRCLineSize = 1024 * 1024
+
+// scanIRCLines is a bufio.SplitFunc for IRC framing. Lines end in \r\n (a bare
+// \n is tolerated), and a partial line at the end of a read is held back until
+// the rest of it arrives.
+func scanIRCLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+	}
+	if atEOF {
+		return len(data), bytes.TrimSuffix(data, []byte{'\r'}), nil
+	}
+	return 0, nil, nil
+}
+
+// pongFor builds the reply to a server PING, echoing back whatever token the
+// server sent. Twitch normally uses "tmi.twitch.tv" but doesn't guarantee it.
+func pongFor(ping string) string {
+	_, ping = splitTags(ping)
+	if strings.HasPrefix(ping, ":") {
+		if i := strings.IndexByte(ping, ' '); i >= 0 {
+			ping = ping[i+1:]
+		}
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(ping, "PING"))
+	token = strings.TrimPrefix(token, ":")
+	if token == "" {
+		token = "tmi.twitch.tv"
+	}
+	return "PONG :" + token + "\r\n"
+}
+
+// isReconnect reports whether data is Twitch asking us to move to another
+// server, sent a while before it drops the connection for maintenance
+func isReconnect(data string) bool {
+	return ircCommand(data) == "RECONNECT"
+}
+
+// ircCommand returns the command of a line, e.g. "PRIVMSG" or "366", past
+// the tags and prefix. Matching on it instead of searching the whole line
+// keeps chat text like "pls RECONNECT" from being taken for a command.
+func ircCommand(data string) string {
+	_, rest := splitTags(data)
+	if strings.HasPrefix(rest, ":") {
+		i := strings.IndexByte(rest, ' ')
+		if i < 0 {
+			return ""
+		}
+		rest = rest[i+1:]
+	}
+	if i := strings.IndexByte(rest, ' '); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
+
+// splitTags splits an IRC line into its IRCv3 tags and the rest of the line.
+func splitTags(data string) (map[string]string, string) {
+	tags := make(map[string]string)
+	if !strings.HasPrefix(data, "@") {
+		return tags, data
+	}
+	spaceIdx := strings.Index(data, " ")
+	if spaceIdx == -1 {
+		return tags, ""
+	}
+	for _, tag := range strings.Split(data[1:spaceIdx], ";") {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) == 2 {
+			tags[kv[0]] = kv[1]
+		}
+	}
+	return tags, data[spaceIdx+1:]
+}
+
+func (c *Client) markJoined() {
+	c.mu.Lock()
+	alreadyJoined := c.joined
+	c.joined = true
+	c.mu.Unlock()
+
+	if alreadyJoined {
+		return
+	}
+	logDebugf("JOIN confirmed for %s", c.channel)
+	select {
+	case c.readyChan <- struct{}{}:
+	default:
+	}
+}
+
+// parseJoinFailure returns a *JoinError if the NOTICE means our JOIN was refused
+func (c *Client) parseJoinFailure(data string) error {
+	tags, payload := splitTags(data)
+	msgID := tags["msg-id"]
+	if !joinFailureMsgIDs[msgID] {
+		return nil
+	}
+
+	reason := msgID
+	if colonIdx := strings.Index(payload, " :"); colonIdx != -1 {
+		reason = payload[colonIdx+2:]
+	}
+	return &JoinError{Channel: c.channel, MsgID: msgID, Reason: reason}
+}
+
+func (c *Client) parseNotice(data string) *Notice {
+	tags, payload := splitTags(data)
+
+	// format: :tmi.twitch.tv NOTICE #channel :This room is now in slow mode.
+	parts := strings.SplitN(payload, " NOTICE ", 2)
+	if len(parts) < 2 {
+		return nil
+	}
+
+	notice := &Notice{
+		MsgID:     tags["msg-id"],
+		Timestamp: time.Now(),
+	}
+	remaining := parts[1]
+	if colonIdx := strings.Index(remaining, " :"); colonIdx != -1 {
+		notice.Channel = remaining[:colonIdx]
+		notice.Content = remaining[colonIdx+2:]
+	} else {
+		notice.Channel = remaining
+	}
+
+	if notice.Content == "" {
+		return nil
+	}
+	return notice
+}
+
+func (c *Client) parseWhisper(data string) *Whisper {
+	tags, payload := splitTags(data)
+
+	// format: :sender!sender@sender.tmi.twitch.tv WHISPER recipient :hello
+	parts := strings.SplitN(payload, " WHISPER ", 2)
+	if len(parts) < 2 {
+		return nil
+	}
+	rest := strings.SplitN(parts[1], " :", 2)
+	if len(rest) < 2 || rest[1] == "" {
+		return nil
+	}
+
+	w := &Whisper{
+		ID:        tags["message-id"],
+		From:      tags["display-name"],
+		To:        rest[0],
+		Content:   rest[1],
+		Timestamp: time.Now(),
+	}
+	if bangIdx := strings.Index(parts[0], "!"); bangIdx > 0 {
+		w.FromLogin = strings.TrimPrefix(parts[0][:bangIdx], ":")
+	}
+	if w.From == "" {
+		w.From = w.FromLogin
+	}
+	return w
+}
+
 func (c *Client) parseUserNotice(data string) *Message {
 	msg := &Message{
 		RawData:   data,
@@ -298,10 +869,12 @@ func (c *Client) parseUserNotice(data string) *Message {
 		}
 	}
 
+	msg.Login = msg.Tags["login"]
+	msg.UserID = msg.Tags["user-id"]
 	if disp, ok := msg.Tags["display-name"]; ok && disp != "" {
 		msg.Username = disp
 	} else {
-		msg.Username = msg.Tags["login"]
+		msg.Username = msg.Login
 	}
 
 	if userContent != "" {
@@ -316,6 +889,157 @@ func (c *Client) parseUserNotice(data string) *Message {
 	return msg
 }
 
+// RawLine is an IRC line as sent or received, for debugging the parsers.
+// Only produced while c.rawIRC is set.
+type RawLine struct {
+	Channel   string
+	Line      string
+	Outgoing  bool
+	Timestamp time.Time
+}
+
+func (c *Client) sendRaw(line string, outgoing bool) {
+	if !c.rawIRC.Load() {
+		return
+	}
+	select {
+	case c.rawChan <- RawLine{Channel: c.channel, Line: redactIRC(line), Outgoing: outgoing, Timestamp: time.Now()}:
+	default:
+	}
+}
+
+// redactIRC hides the oauth token in PASS lines
+func redactIRC(line string) string {
+	if strings.HasPrefix(line, "PASS ") {
+		return "PASS oauth:***"
+	}
+	return line
+}
+
+// Presence is a user joining or leaving chat, from the membership capability.
+// Twitch batches JOIN/PART (roughly every 10s) and only sends the initial
+// NAMES list for channels under 1000 chatters, so in big channels this is
+// at best a partial picture and often nothing at all.
+type Presence struct {
+	Channel  string
+	Username string
+	Joined   bool
+}
+
+func (c *Client) parsePresence(data string) []Presence {
+	_, payload := splitTags(data)
+	fields := strings.Fields(payload)
+	if len(fields) < 3 {
+		return nil
+	}
+
+	c.mu.RLock()
+	self := c.username
+	c.mu.RUnlock()
+
+	switch fields[1] {
+	case "JOIN", "PART":
+		// :user!user@user.tmi.twitch.tv JOIN #channel
+		user := strings.TrimPrefix(fields[0], ":")
+		if idx := strings.Index(user, "!"); idx != -1 {
+			user = user[:idx]
+		}
+		if user == "" || user == self {
+			return nil
+		}
+		return []Presence{{Channel: fields[2], Username: user, Joined: fields[1] == "JOIN"}}
+	case "353":
+		// :nick.tmi.twitch.tv 353 nick = #channel :user1 user2 ...
+		idx := strings.Index(payload, " :")
+		if idx == -1 || len(fields) < 5 {
+			return nil
+		}
+		var result []Presence
+		for _, user := range strings.Fields(payload[idx+2:]) {
+			if user != self {
+				result = append(result, Presence{Channel: fields[4], Username: user, Joined: true})
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// ModAction is a ban, timeout, chat clear or deleted message. Twitch IRC
+// doesn't say which moderator did it, so there's no "by" field.
+type ModAction struct {
+	Channel   string
+	Action    string // ban, timeout, clear or delete
+	Target    string // user, empty for a chat clear
+	Duration  string // timeout seconds
+	MessageID string // deleted message
+	Content   string // deleted message text
+	Timestamp time.Time
+}
+
+// sendModAction parses a CLEARCHAT/CLEARMSG line for the mod log. Only done
+// when $modlog is on and we're logged in, anonymous users can't moderate.
+func (c *Client) sendModAction(data string) {
+	c.mu.RLock()
+	authenticated := c.oauthToken != ""
+	c.mu.RUnlock()
+	if !c.config.ModLog || !authenticated {
+		return
+	}
+
+	action := parseModAction(data)
+	if action == nil {
+		return
+	}
+	select {
+	case c.modActionChan <- *action:
+	default:
+	}
+}
+
+func parseModAction(data string) *ModAction {
+	tags, payload := splitTags(data)
+
+	var command string
+	for _, cmd := range []string{"CLEARCHAT", "CLEARMSG"} {
+		if strings.Contains(payload, " "+cmd+" ") {
+			command = cmd
+			break
+		}
+	}
+	if command == "" {
+		return nil
+	}
+
+	// :tmi.twitch.tv CLEARCHAT #channel :target
+	// :tmi.twitch.tv CLEARMSG #channel :deleted text
+	remaining := strings.SplitN(payload, " "+command+" ", 2)[1]
+	action := &ModAction{Channel: remaining, Timestamp: time.Now()}
+	var trailing string
+	if idx := strings.Index(remaining, " :"); idx != -1 {
+		action.Channel = remaining[:idx]
+		trailing = remaining[idx+2:]
+	}
+
+	switch {
+	case command == "CLEARMSG":
+		action.Action = "delete"
+		action.Target = tags["login"]
+		action.MessageID = tags["target-msg-id"]
+		action.Content = trailing
+	case trailing == "":
+		action.Action = "clear"
+	case tags["ban-duration"] != "":
+		action.Action = "timeout"
+		action.Target = trailing
+		action.Duration = tags["ban-duration"]
+	default:
+		action.Action = "ban"
+		action.Target = trailing
+	}
+	return action
+}
+
 func (c *Client) parseClearChat(data string) *Message {
 	msg := &Message{
 		RawData:   data,
@@ -397,13 +1121,19 @@ func (c *Client) parsePrivMsg(data string) *Message {
 		return nil
 	}
 
+	// prefix is :login!login@login.tmi.twitch.tv
+	if bangIdx := strings.Index(parts[0], "!"); bangIdx != -1 {
+		msg.Login = strings.ToLower(strings.TrimPrefix(parts[0][:bangIdx], ":"))
+	}
+	msg.UserID = msg.Tags["user-id"]
+
 	if disp, ok := msg.Tags["display-name"]; ok && disp != "" {
 		msg.Username = disp
 	} else {
-		userPart := parts[0]
-		if bangIdx := strings.Index(userPart, "!"); bangIdx != -1 {
-			msg.Username = userPart[1:bangIdx]
-		}
+		msg.Username = msg.Login
+	}
+	if msg.Login == "" {
+		msg.Login = strings.ToLower(msg.Username)
 	}
 
 	contentParts := strings.SplitN(parts[1], " :", 2)
@@ -412,21 +1142,36 @@ func (c *Client) parsePrivMsg(data string) *Message {
 		msg.Content = contentParts[1]
 	}
 
+	// /me arrives as \x01ACTION text\x01, emote positions are relative to the inner text
+	if strings.HasPrefix(msg.Content, "\x01ACTION ") {
+		msg.Content = strings.TrimSuffix(strings.TrimPrefix(msg.Content, "\x01ACTION "), "\x01")
+		msg.IsAction = true
+	}
+
+	// palette colors get the same treatment, blue on a dark background or
+	// yellow on a light one is no easier to read when Twitch picked it
 	if col, ok := msg.Tags["color"]; ok && col != "" {
-		msg.UserColor = convertToLightIfDark(col)
+		msg.UserColor = adjustNameColor(col, c.config.Background, c.config.MinContrast)
 	} else {
-		msg.UserColor = getTwitchDefaultColor(msg.Username)
+		msg.UserColor = adjustNameColor(getTwitchDefaultColor(msg.Username, c.config.Palette),
+			c.config.Background, c.config.MinContrast)
 	}
 
-	return msg
-}
+	msg.Badges = parseBadges(msg.Tags["badges"], msg.Tags["badge-info"])
+	msg.IsFirstMessage = msg.Tags["first-msg"] == "1"
+	msg.IsReturningChatter = msg.Tags["returning-chatter"] == "1"
 
-func (c *Client) parseRewardRedemption(data string) *RewardRedemption {
-	msg := c.parsePrivMsg(data)
-	if msg == nil {
-		return nil
+	// Shared chat tags the origin on copies in every channel of the session,
+	// including the one it was sent in
+	if src := msg.Tags["source-room-id"]; src != "" && src != msg.Tags["room-id"] {
+		msg.SourceRoomID = src
+		msg.SourceMessageID = msg.Tags["source-id"]
 	}
 
+	return msg
+}
+
+func rewardFromMessage(msg *Message, data string) *RewardRedemption {
 	return &RewardRedemption{
 		RewardID:   msg.Tags["custom-reward-id"],
 		Username:   msg.Username,
@@ -441,7 +1186,55 @@ func (c *Client) GetMessages(n int) []Message            { return c.messageBuffe
 func (c *Client) GetAllMessages() []Message              { return c.messageBuffer.GetAll() }
 func (c *Client) MessageChannel() <-chan Message         { return c.messageChan }
 func (c *Client) RewardChannel() <-chan RewardRedemption { return c.rewardChan }
+func (c *Client) NoticeChannel() <-chan Notice           { return c.noticeChan }
+func (c *Client) WhisperChannel() <-chan Whisper         { return c.whisperChan }
+func (c *Client) EmoteSetsChannel() <-chan []string      { return c.emoteSetsChan }
+func (c *Client) ModActionChannel() <-chan ModAction     { return c.modActionChan }
+func (c *Client) PresenceChannel() <-chan Presence       { return c.presenceChan }
+func (c *Client) RawChannel() <-chan RawLine             { return c.rawChan }
 func (c *Client) ErrorChannel() <-chan error             { return c.errorChan }
+func (c *Client) ReadyChannel() <-chan struct{}          { return c.readyChan }
+
+func (c *Client) IsJoined() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.joined
+}
+
+// SendMessage sends a chat message to the client's channel. Sends are
+// throttled per connection to stay under Twitch's limit (20 per 30s for
+// normal users); anything over the limit is rejected with ErrRateLimited.
+func (c *Client) SendMessage(text string) error {
+	c.mu.RLock()
+	conn := c.conn
+	connected := c.connected
+	authenticated := c.oauthToken != ""
+	c.mu.RUnlock()
+
+	if conn == nil || !connected {
+		return fmt.Errorf("not connected to %s", c.channel)
+	}
+	if !authenticated {
+		return ErrNotAuthenticated
+	}
+
+	// a stray newline would let the text smuggle in another IRC command
+	text = strings.TrimSpace(strings.NewReplacer("\r", " ", "\n", " ").Replace(text))
+	if text == "" {
+		return fmt.Errorf("empty message")
+	}
+
+	if !c.sendLimiter.Allow() {
+		return fmt.Errorf("%w, try again in %s", ErrRateLimited, c.sendLimiter.Delay().Round(time.Second))
+	}
+
+	line := fmt.Sprintf("PRIVMSG %s :%s", c.channel, text)
+	if _, err := fmt.Fprintf(conn, "%s\r\n", line); err != nil {
+		return fmt.Errorf("send failed: %w", err)
+	}
+	c.sendRaw(line, true)
+	return nil
+}
 
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
@@ -449,6 +1242,20 @@ func (c *Client) IsConnected() bool {
 	return c.connected
 }
 
+// Healthy reports whether the client is in the channel and has heard from
+// the server within readIdleTimeout. Keepalive PINGs get a PONG back, so a
+// working connection never goes that long without a line.
+func (c *Client) Healthy() bool {
+	c.mu.RLock()
+	ok := c.connected && c.joined
+	lastRead := &c.lastRead
+	if c.mux != nil {
+		lastRead = &c.mux.lastRead
+	}
+	c.mu.RUnlock()
+	return ok && time.Since(time.Unix(0, lastRead.Load())) < readIdleTimeout
+}
+
 func (c *Client) Stop() {
 	c.mu.Lock()
 	if c.stopped {
@@ -457,33 +1264,32 @@ func (c *Client) Stop() {
 	}
 	c.stopped = true
 	c.connected = false
+	close(c.stopChan)
+	if c.mux != nil {
+		c.mu.Unlock()
+		c.mux.part(c)
+		return
+	}
 	if c.conn != nil {
 		c.conn.Close()
 	}
 	c.mu.Unlock()
 }
 
-func convertToLightIfDark(hexColor string) string {
-	color := strings.TrimPrefix(hexColor, "#")
-	if len(color) != 6 {
-		return hexColor
-	}
-	r, _ := strconv.ParseInt(color[0:2], 16, 0)
-	g, _ := strconv.ParseInt(color[2:4], 16, 0)
-	b, _ := strconv.ParseInt(color[4:6], 16, 0)
-	if 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) < 128 {
-		r += int64(float64(255-r) * 0.4)
-		g += int64(float64(255-g) * 0.4)
-		b += int64(float64(255-b) * 0.4)
-	}
-	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+// Twitch's own defaults, used unless $palette is set
+var defaultNamePalette = []string{
+	"#FF0000", "#0000FF", "#008000", "#B22222", "#FF7F50",
+	"#9ACD32", "#FF4500", "#2E8B57", "#DAA520", "#D2691E",
+	"#5F9EA0", "#1E90FF", "#FF69B4", "#8A2BE2", "#00FF7F",
 }
 
-func getTwitchDefaultColor(username string) string {
-	colors := []string{
-		"#FF0000", "#0000FF", "#008000", "#B22222", "#FF7F50",
-		"#9ACD32", "#FF4500", "#2E8B57", "#DAA520", "#D2691E",
-		"#5F9EA0", "#1E90FF", "#FF69B4", "#8A2BE2", "#00FF7F",
+// getTwitchDefaultColor picks a color from palette ($palette, Twitch's when
+// empty) by a hash of the username, so a user keeps the same color as long
+// as the palette doesn't change
+func getTwitchDefaultColor(username string, palette []string) string {
+	colors := defaultNamePalette
+	if len(palette) > 0 {
+		colors = palette
 	}
 	if username == "" {
 		return colors[0]
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient is a client on the default config
+func newTestClient(channel string) *Client {
+	config := defaultTwitchConfig()
+	return NewClient(channel, 10, &config)
+}
+
+func TestIsReconnect(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{":tmi.twitch.tv RECONNECT", true},
+		{"RECONNECT", true},
+		{"@badge-info=;color=#FF0000;display-name=viewer :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :pls RECONNECT", false},
+		{":viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :RECONNECT", false},
+		{":tmi.twitch.tv NOTICE #chan :RECONNECT", false},
+	}
+	for _, tt := range tests {
+		if got := isReconnect(tt.line); got != tt.want {
+			t.Errorf("isReconnect(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestIRCCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{":tmi.twitch.tv 433 * justinfan123 :Nickname is already in use", "433"},
+		{":viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :room 433 is free", "PRIVMSG"},
+		{"@msg-id=resub;system-msg=viewer\\ssubscribed :tmi.twitch.tv USERNOTICE #chan :watched 433 days", "USERNOTICE"},
+		{"PING :tmi.twitch.tv", "PING"},
+		{"@tags=1", ""},
+		{":tmi.twitch.tv", ""},
+	}
+	for _, tt := range tests {
+		if got := ircCommand(tt.line); got != tt.want {
+			t.Errorf("ircCommand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestHandleLineWhisperRouting(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		whispers int
+		messages int
+	}{
+		{
+			"whisper",
+			"@display-name=Friend;message-id=1 :friend!friend@friend.tmi.twitch.tv WHISPER me :hi",
+			1, 0,
+		},
+		{
+			"whisper mentioning PRIVMSG",
+			"@display-name=Friend;message-id=2 :friend!friend@friend.tmi.twitch.tv WHISPER me :a PRIVMSG #chan :fake",
+			1, 0,
+		},
+		{
+			"resub mentioning WHISPER",
+			"@display-name=Spoofer;login=spoofer;msg-id=resub;system-msg=resubbed :tmi.twitch.tv USERNOTICE #chan :lol WHISPER me :hi",
+			0, 1,
+		},
+		{
+			"deleted message mentioning WHISPER",
+			"@login=spoofer;target-msg-id=abc :tmi.twitch.tv CLEARMSG #chan :lol WHISPER me :hi",
+			0, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient("#chan")
+			c.SetCredentials("me", "token")
+			c.handleLine(tt.line)
+			if got := len(c.whisperChan); got != tt.whispers {
+				t.Errorf("got %d whispers, want %d", got, tt.whispers)
+			}
+			if got := len(c.messageChan); got != tt.messages {
+				t.Errorf("got %d chat messages, want %d", got, tt.messages)
+			}
+		})
+	}
+}
+
+func TestHandleLineUserNoticeText(t *testing.T) {
+	// sub messages are free text and can contain anything a command looks like
+	for _, text := range []string{"watched 433 days", "got the NOTICE lol", "366 days of subs"} {
+		c := newTestClient("#chan")
+		c.SetCredentials("me", "token")
+		c.handleLine("@login=viewer;msg-id=resub;system-msg=viewer\\sresubscribed :tmi.twitch.tv USERNOTICE #chan :" + text)
+		if got := len(c.messageChan); got != 1 {
+			t.Errorf("resub %q: got %d chat messages, want 1", text, got)
+		}
+		if got := len(c.errorChan); got != 0 {
+			t.Errorf("resub %q: got %d errors, want 0", text, got)
+		}
+		if c.IsJoined() {
+			t.Errorf("resub %q: marked the channel joined", text)
+		}
+	}
+}
+
+func TestHandleLineEmoteSets(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want int
+	}{
+		{"globaluserstate", "@emote-sets=0,300374282;user-id=1 :tmi.twitch.tv GLOBALUSERSTATE", 1},
+		{"userstate", "@emote-sets=0,300374282;mod=0 :tmi.twitch.tv USERSTATE #chan", 1},
+		{"resub mentioning USERSTATE", "@login=viewer;msg-id=resub;system-msg=resubbed :tmi.twitch.tv USERNOTICE #chan :my USERSTATE is great", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient("#chan")
+			c.SetCredentials("me", "token")
+			c.handleLine(tt.line)
+			if got := len(c.emoteSetsChan); got != tt.want {
+				t.Errorf("got %d emote set updates, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleLineRewards(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		rewards   int
+		messages  int
+		userInput string
+	}{
+		{
+			"redemption with text",
+			"@badge-info=;badges=;color=#1E90FF;custom-reward-id=6cd3e7a6-9b5b-4b8a-8f3e-1c2d3e4f5a6b;display-name=Viewer;emotes=;first-msg=0;id=b34ccfc7-4977-403a-8a94-33c6bac34fb8;mod=0;room-id=12345;subscriber=0;tmi-sent-ts=1700000000000;turbo=0;user-id=67890;user-type= :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :play the song",
+			1, 0, "play the song",
+		},
+		{
+			"empty custom-reward-id",
+			"@badge-info=;badges=;color=;custom-reward-id=;display-name=Viewer;emotes=;id=c44ccfc7-4977-403a-8a94-33c6bac34fb8;room-id=12345;user-id=67890 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :hello",
+			0, 1, "",
+		},
+		{
+			"no custom-reward-id",
+			"@badge-info=;badges=;color=;display-name=Viewer;emotes=;id=d54ccfc7-4977-403a-8a94-33c6bac34fb8;room-id=12345;user-id=67890 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :custom-reward-id=abc",
+			0, 1, "",
+		},
+		{
+			"reward tag on a non-PRIVMSG",
+			"@custom-reward-id=6cd3e7a6;login=viewer;msg-id=resub;system-msg=resubbed :tmi.twitch.tv USERNOTICE #chan :hi",
+			0, 1, "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient("#chan")
+			c.handleLine(tt.line)
+			if got := len(c.rewardChan); got != tt.rewards {
+				t.Fatalf("got %d rewards, want %d", got, tt.rewards)
+			}
+			if got := len(c.messageChan); got != tt.messages {
+				t.Errorf("got %d chat messages, want %d", got, tt.messages)
+			}
+			if tt.rewards > 0 {
+				reward := <-c.rewardChan
+				if reward.UserInput != tt.userInput {
+					t.Errorf("got user input %q, want %q", reward.UserInput, tt.userInput)
+				}
+				if reward.Username != "Viewer" {
+					t.Errorf("got username %q, want %q", reward.Username, "Viewer")
+				}
+			}
+		})
+	}
+}
+
+func TestScanIRCLines(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		atEOF       bool
+		wantAdvance int
+		wantToken   string
+		wantLine    bool
+	}{
+		{"crlf", "PING :tmi.twitch.tv\r\nPING", false, 21, "PING :tmi.twitch.tv", true},
+		{"bare lf", "PING :tmi.twitch.tv\nPING", false, 20, "PING :tmi.twitch.tv", true},
+		{"partial line waits for more", ":tmi.twitch.tv 366 me #chan :End of /NA", false, 0, "", false},
+		{"partial line at eof", ":tmi.twitch.tv RECONNECT\r", true, 25, ":tmi.twitch.tv RECONNECT", true},
+		{"empty at eof", "", true, 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advance, token, err := scanIRCLines([]byte(tt.data), tt.atEOF)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if advance != tt.wantAdvance {
+				t.Errorf("advance = %d, want %d", advance, tt.wantAdvance)
+			}
+			if (token != nil) != tt.wantLine || string(token) != tt.wantToken {
+				t.Errorf("token = %q, want %q", token, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestListenOverlongLine(t *testing.T) {
+	r, w := io.Pipe()
+	c := newTestClient("#chan")
+	c.ConnectSource(r)
+	c.Start()
+	defer c.Stop()
+
+	// past bufio's 64KB default, used to stop the scanner with ErrTooLong
+	long := strings.Repeat("a", 100*1024)
+	go func() {
+		// split mid-line, the rest of it arrives in a later read
+		io.WriteString(w, "@id=1 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :"+long[:1000])
+		io.WriteString(w, long[1000:]+"\r\n")
+		io.WriteString(w, "@id=2 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #chan :after\n")
+	}()
+
+	for _, want := range []string{long, "after"} {
+		select {
+		case msg := <-c.MessageChannel():
+			if msg.Content != want {
+				t.Fatalf("got a %d byte message, want %d bytes", len(msg.Content), len(want))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a message")
+		}
+	}
+	if !c.IsConnected() {
+		t.Error("client disconnected after an over-long line")
+	}
+}
+
+func TestPongFor(t *testing.T) {
+	tests := []struct {
+		ping string
+		want string
+	}{
+		{"PING :tmi.twitch.tv", "PONG :tmi.twitch.tv\r\n"},
+		{"PING :irc-ws.chat.twitch.tv", "PONG :irc-ws.chat.twitch.tv\r\n"},
+		{"PING tmi.twitch.tv", "PONG :tmi.twitch.tv\r\n"},
+		{"PING", "PONG :tmi.twitch.tv\r\n"},
+		{"@tmi-sent-ts=1700000000000 PING :tmi.twitch.tv", "PONG :tmi.twitch.tv\r\n"},
+		{"@tmi-sent-ts=1700000000000 :tmi.twitch.tv PING :12345", "PONG :12345\r\n"},
+	}
+	for _, tt := range tests {
+		if got := pongFor(tt.ping); got != tt.want {
+			t.Errorf("pongFor(%q) = %q, want %q", tt.ping, got, tt.want)
+		}
+		if got := ircCommand(tt.ping); got != "PING" {
+			t.Errorf("ircCommand(%q) = %q, want PING", tt.ping, got)
+		}
+	}
+}
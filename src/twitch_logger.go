@@ -5,15 +5,57 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-func createFileForChannel(channel string) *os.File {
-	t := time.Now()
-	formatted := fmt.Sprintf("%d-%02d-%02d",
-		t.Year(), t.Month(), t.Day())
+// chatLog is a channel's open log file and the day it's for
+type chatLog struct {
+	file *os.File
+	date string
+}
+
+// logMessage appends msg to the channel's chat log, opening it on first use
+// and moving on to a new file when the day changes
+func (a *App) logMessage(channel string, msg Message) {
+	a.loggersMu.Lock()
+	defer a.loggersMu.Unlock()
+	if a.shuttingDown.Load() {
+		// closeChatLogs already ran, don't leak a reopened file
+		return
+	}
 
-	dir := filepath.Join("logs", channel)
+	date := msg.Timestamp.Format("2006-01-02")
+	logger, ok := a.loggers[channel]
+	if ok && logger.date != date {
+		logger.file.Close()
+		ok = false
+	}
+	if !ok {
+		logger = &chatLog{file: a.createFileForChannel(channel, date), date: date}
+		a.loggers[channel] = logger
+	}
+	fmt.Fprintf(logger.file, "[%s] %s: %s\n", a.formatLogTimestamp(msg.Timestamp),
+		msg.Username, msg.Content)
+	logger.file.Sync()
+}
+
+// closeChatLogs flushes and closes every open chat log
+func (a *App) closeChatLogs() {
+	a.loggersMu.Lock()
+	defer a.loggersMu.Unlock()
+	for channel, logger := range a.loggers {
+		logger.file.Sync()
+		if err := logger.file.Close(); err != nil {
+			logWarnf("Closing chat log for %s: %v", channel, err)
+		}
+		delete(a.loggers, channel)
+	}
+}
+
+func (a *App) createFileForChannel(channel, formatted string) *os.File {
+	dir := a.dataPath("logs", channel)
 	filepath := filepath.Join(dir, formatted+"_log.txt")
 
 	os.MkdirAll(dir, 0700)
@@ -21,6 +63,117 @@ func createFileForChannel(channel string) *os.File {
 	if err != nil {
 		log.Fatalf("error opening file: %v", err)
 	}
-	log.Printf("Created log file for %s with path %s", channel, filepath)
+	logDebugf("Created log file for %s with path %s", channel, filepath)
 	return f
 }
+
+// formatTimestamp formats message times for the UI and log lines ($timeformat)
+func (a *App) formatTimestamp(t time.Time) string {
+	return t.Format(a.config.TimeFormat)
+}
+
+// formatLogTimestamp is formatTimestamp with the date in front if $logdate is
+// set
+func (a *App) formatLogTimestamp(t time.Time) string {
+	if a.config.LogDate {
+		return t.Format("2006-01-02 ") + a.formatTimestamp(t)
+	}
+	return a.formatTimestamp(t)
+}
+
+// logModAction appends to logs/<channel>/<date>_modlog.txt, next to the chat log
+func (a *App) logModAction(action ModAction) {
+	channel := strings.TrimPrefix(action.Channel, "#")
+	dir := a.dataPath("logs", channel)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logErrorf("Failed to create mod log dir for %s: %v", channel, err)
+		return
+	}
+
+	path := filepath.Join(dir, action.Timestamp.Format("2006-01-02")+"_modlog.txt")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		logErrorf("Failed to open mod log %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	line := fmt.Sprintf("[%s] %s", a.formatLogTimestamp(action.Timestamp), strings.ToUpper(action.Action))
+	if action.Target != "" {
+		line += " " + action.Target
+	}
+	if action.Duration != "" {
+		line += " for " + action.Duration + "s"
+	}
+	if action.Content != "" {
+		line += fmt.Sprintf(": %q", action.Content)
+	}
+	fmt.Fprintln(f, line)
+}
+
+// logReward appends to logs/<channel>/<date>_rewards.txt. Only redemptions
+// that carry user text reach IRC, so that's all that can be logged.
+func (a *App) logReward(channel string, reward RewardRedemption) {
+	channel = strings.TrimPrefix(channel, "#")
+	dir := a.dataPath("logs", channel)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logErrorf("Failed to create rewards log dir for %s: %v", channel, err)
+		return
+	}
+
+	path := filepath.Join(dir, reward.Timestamp.Format("2006-01-02")+"_rewards.txt")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		logErrorf("Failed to open rewards log %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "[%s] %s redeemed %s (%s): %s\n", a.formatLogTimestamp(reward.Timestamp),
+		reward.Username, reward.RewardName, reward.RewardID, reward.UserInput)
+}
+
+// Raw IRC lines from every channel go to one file, logs/<date>_rawirc.txt.
+// Kept open while the debug stream is on, it's a lot of lines. If the open
+// fails it isn't retried until the date changes.
+type rawLog struct {
+	sync.Mutex
+	file *os.File
+	date string
+}
+
+func (a *App) logRawIRC(raw RawLine) {
+	a.rawIRCLog.Lock()
+	defer a.rawIRCLog.Unlock()
+
+	date := raw.Timestamp.Format("2006-01-02")
+	if a.rawIRCLog.date != date {
+		if a.rawIRCLog.file != nil {
+			a.rawIRCLog.file.Close()
+			a.rawIRCLog.file = nil
+		}
+		a.rawIRCLog.date = date
+		f, err := a.openRawIRCLog(date)
+		if err != nil {
+			logErrorf("Failed to open raw IRC log: %v", err)
+			return
+		}
+		a.rawIRCLog.file = f
+	}
+	if a.rawIRCLog.file == nil {
+		return
+	}
+
+	direction := "<"
+	if raw.Outgoing {
+		direction = ">"
+	}
+	fmt.Fprintf(a.rawIRCLog.file, "[%s] %s %s %s\n", raw.Timestamp.Format("15:04:05.000"), raw.Channel, direction, raw.Line)
+}
+
+func (a *App) openRawIRCLog(date string) (*os.File, error) {
+	if err := os.MkdirAll(a.dataPath("logs"), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(a.dataPath("logs", date+"_rawirc.txt"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
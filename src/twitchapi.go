@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const gqlURL = "https://gql.twitch.tv/gql"
+
+// Shared by every GQL call so connections get reused
+var gqlClient = &http.Client{Timeout: 10 * time.Second}
+
+var ErrTwitchAPIDegraded = errors.New("twitch api degraded, backing off")
+
+const (
+	breakerThreshold   = 5 // consecutive failures before backing off
+	breakerCooldown    = 30 * time.Second
+	breakerMaxCooldown = 10 * time.Minute
+)
+
+// circuitBreaker stops requests after repeated failures. Once the cooldown
+// passes requests go through again; another failure doubles the cooldown,
+// a success closes the breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	cooldown  time.Duration
+	openUntil time.Time
+}
+
+func (cb *circuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return time.Now().After(cb.openUntil)
+}
+
+// Failure records a failed request and reports whether it opened the breaker
+func (cb *circuitBreaker) Failure() (bool, time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures++
+	if cb.failures < breakerThreshold {
+		return false, 0
+	}
+
+	if cb.cooldown == 0 {
+		cb.cooldown = breakerCooldown
+	} else {
+		cb.cooldown = min(cb.cooldown*2, breakerMaxCooldown)
+	}
+	cb.openUntil = time.Now().Add(cb.cooldown)
+	return true, cb.cooldown
+}
+
+// Success resets the breaker and reports whether it had been open
+func (cb *circuitBreaker) Success() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	wasOpen := cb.cooldown > 0
+	cb.failures = 0
+	cb.cooldown = 0
+	cb.openUntil = time.Time{}
+	return wasOpen
+}
+
+// gqlRequest posts a GQL query and decodes the response into out. Failures
+// (network, 429, 5xx) count towards the breaker, while it's open requests
+// fail fast with ErrTwitchAPIDegraded.
+func (a *App) gqlRequest(query string, out interface{}) error {
+	if !a.gqlBreaker.Allow() {
+		return ErrTwitchAPIDegraded
+	}
+
+	err := a.doGQLRequest(query, out)
+	if err != nil {
+		if !retryable(err) {
+			// Twitch answered, just not with something we can use
+			return err
+		}
+		if opened, cooldown := a.gqlBreaker.Failure(); opened {
+			logWarnf("Twitch API failing (%v), backing off for %v", err, cooldown)
+			a.emit("twitch-api-degraded", map[string]interface{}{
+				"degraded": true,
+				"retryIn":  int(cooldown.Seconds()),
+				"error":    err.Error(),
+			})
+		}
+		return err
+	}
+
+	if a.gqlBreaker.Success() {
+		logInfof("Twitch API recovered")
+		a.emit("twitch-api-degraded", map[string]interface{}{
+			"degraded": false,
+		})
+	}
+	return nil
+}
+
+func (a *App) doGQLRequest(query string, out interface{}) error {
+	req, err := http.NewRequest("POST", gqlURL, strings.NewReader(query))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Client-ID", "kimne78kx3ncx6brgo4mv6wki5h1ko")
+	req.Header.Set("Content-Type", "application/json")
+
+	a.metrics.apiCalls.Add(1)
+	resp, err := gqlClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("gql: %w: %w", ErrConnect, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &APIStatusError{API: "gql", Code: resp.StatusCode}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("gql: %w: %w", ErrDecode, err)
+	}
+	return nil
+}
+
+// channelExists looks the login up on Twitch. Only a definite "no such user"
+// returns false with a nil error.
+func (a *App) channelExists(login string) (bool, error) {
+	query := fmt.Sprintf(`{"query":"query { user(login:\"%s\") { id } }"}`, login)
+
+	var result struct {
+		Data struct {
+			User *struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+
+	if err := a.gqlRequest(query, &result); err != nil {
+		return false, err
+	}
+	return result.Data.User != nil, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Twitch emotes the logged-in account can send (subs, follower emotes,
+// globals, ...), from the emote-sets tag of USERSTATE. Kept apart from the
+// 7TV/BTTV/FFZ emotes, which anyone can use.
+type userEmoteSets struct {
+	sync.Mutex
+	sets   []string // sorted set ids the emotes are for
+	emotes []EmoteInfo
+}
+
+// Sets looked up per GQL request
+const emoteSetsPerQuery = 25
+
+// updateUserEmotes refetches the user's emotes when their sets changed.
+// nil clears them, e.g. after switching accounts.
+func (a *App) updateUserEmotes(sets []string) {
+	sets = slices.Clone(sets)
+	sort.Strings(sets)
+
+	a.userEmotes.Lock()
+	if slices.Equal(a.userEmotes.sets, sets) {
+		a.userEmotes.Unlock()
+		return
+	}
+	a.userEmotes.sets = sets
+	a.userEmotes.emotes = nil
+	a.userEmotes.Unlock()
+
+	if len(sets) == 0 {
+		a.emit("user-emotes-updated", 0)
+		return
+	}
+
+	go func() {
+		emotes, err := a.fetchEmoteSets(sets)
+		if err != nil {
+			logWarnf("Couldn't fetch your emote sets: %v", err)
+		}
+
+		a.userEmotes.Lock()
+		if !slices.Equal(a.userEmotes.sets, sets) {
+			// the sets changed again while fetching
+			a.userEmotes.Unlock()
+			return
+		}
+		a.userEmotes.emotes = emotes
+		a.userEmotes.Unlock()
+
+		logInfof("Loaded %d emotes from %d emote sets", len(emotes), len(sets))
+		a.emit("user-emotes-updated", len(emotes))
+	}()
+}
+
+func (a *App) fetchEmoteSets(sets []string) ([]EmoteInfo, error) {
+	var emotes []EmoteInfo
+	seen := make(map[string]bool)
+	for chunk := range slices.Chunk(sets, emoteSetsPerQuery) {
+		fields := make([]string, 0, len(chunk))
+		for i, id := range chunk {
+			fields = append(fields, fmt.Sprintf(`s%d: emoteSet(id:\"%s\") { emotes { id token } }`, i, id))
+		}
+		query := fmt.Sprintf(`{"query":"query { %s }"}`, strings.Join(fields, " "))
+
+		var result struct {
+			Data map[string]*struct {
+				Emotes []struct {
+					ID    string `json:"id"`
+					Token string `json:"token"`
+				} `json:"emotes"`
+			} `json:"data"`
+		}
+		if err := a.gqlRequest(query, &result); err != nil {
+			return emotes, err
+		}
+		for _, set := range result.Data {
+			if set == nil {
+				continue
+			}
+			for _, e := range set.Emotes {
+				if seen[e.ID] {
+					continue
+				}
+				seen[e.ID] = true
+				url := fmt.Sprintf("https://static-cdn.jtvnw.net/emoticons/v2/%s/default/dark/1.0", e.ID)
+				emotes = append(emotes, EmoteInfo{
+					ID:       e.ID,
+					Name:     e.Token,
+					URL:      url,
+					ImageURL: url,
+					Provider: "twitch",
+				})
+			}
+		}
+	}
+	sort.Slice(emotes, func(i, j int) bool { return emotes[i].Name < emotes[j].Name })
+	return emotes, nil
+}
+
+// GetUserEmotes lists the Twitch emotes the logged-in account can send, for
+// the emote picker. Empty when anonymous. Images are on Twitch's CDN (URL),
+// they're not downloaded.
+func (a *App) GetUserEmotes() []EmoteInfo {
+	a.userEmotes.Lock()
+	defer a.userEmotes.Unlock()
+	return append([]EmoteInfo{}, a.userEmotes.emotes...)
+}
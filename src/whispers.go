@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+const (
+	// Whispers kept for GetWhispers
+	maxWhispers = 200
+	// Whispers without a message-id count as copies within this
+	whisperDedupWindow = 2 * time.Second
+)
+
+// addWhisper puts w in the inbox, false if it's a copy already there
+func (a *App) addWhisper(w Whisper) bool {
+	a.whispersMu.Lock()
+	defer a.whispersMu.Unlock()
+	for _, seen := range a.whispers {
+		if w.ID != "" && seen.ID == w.ID {
+			return false
+		}
+		if w.ID == "" && seen.FromLogin == w.FromLogin && seen.Content == w.Content &&
+			w.Timestamp.Sub(seen.Timestamp) < whisperDedupWindow {
+			return false
+		}
+	}
+	a.whispers = append(a.whispers, w)
+	if len(a.whispers) > maxWhispers {
+		a.whispers = a.whispers[len(a.whispers)-maxWhispers:]
+	}
+	return true
+}
+
+// GetWhispers returns the whisper inbox, oldest first. Whispers only arrive
+// when logged in with $oauth.
+func (a *App) GetWhispers() []Whisper {
+	a.whispersMu.Lock()
+	defer a.whispersMu.Unlock()
+	return append([]Whisper(nil), a.whispers...)
+}